@@ -7,12 +7,20 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // Embedded zone database for validating profile timezones
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
 	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/fieldcrypt"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/metrics"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/tracing"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/version"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/router"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
 )
 
 // cmd/api/main.go
@@ -38,11 +46,57 @@ func main() {
 	if err != nil {
 		log.Fatal("Error loading config", zap.Error(err))
 	}
+	logOptions := logger.DefaultOptions(environment)
+	if cfg.Logging.Level != "" {
+		logOptions.Level = cfg.Logging.Level
+	}
+	if len(cfg.Logging.Outputs) > 0 {
+		logOptions.Outputs = cfg.Logging.Outputs
+	}
+	if cfg.Logging.ErrorOutput != "" {
+		logOptions.ErrorOutput = cfg.Logging.ErrorOutput
+	}
+	logOptions.Modules = cfg.Logging.Modules
+	logOptions.Rotation = logger.Rotation{
+		MaxSizeMB:  cfg.Logging.Rotation.MaxSizeMB,
+		MaxAgeDays: cfg.Logging.Rotation.MaxAgeDays,
+		MaxBackups: cfg.Logging.Rotation.MaxBackups,
+		Compress:   cfg.Logging.Rotation.Compress,
+		Interval:   time.Duration(cfg.Logging.Rotation.IntervalHours) * time.Hour,
+	}
+	logOptions.Sampling = logger.Sampling{
+		Initial:    cfg.Logging.Sampling.Initial,
+		Thereafter: cfg.Logging.Sampling.Thereafter,
+		Tick:       time.Duration(cfg.Logging.Sampling.Tick) * time.Second,
+	}
+	configured, err := logger.Configure(logOptions)
+	if err != nil {
+		log.Fatal("Invalid logging config", zap.Error(err))
+	}
+	log = configured
+	if rules, ok := cfg.Logging.Redaction[environment]; ok {
+		if err := logger.SetRedactionRules(logger.RedactionRules{Fields: rules.Fields, Patterns: rules.Patterns}); err != nil {
+			log.Fatal("Invalid log redaction rules", zap.Error(err))
+		}
+	}
 
 	// Context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Initialize tracing before the clients it instruments
+	shutdownTracing, err := tracing.Init(ctx, cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	defer func() {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			log.Error("Error flushing traces", zap.Error(err))
+		}
+	}()
+
 	// Initialize MongoDB
 	mongoClient, err := config.InitMongoDB(ctx, cfg)
 	if err != nil {
@@ -54,15 +108,57 @@ func main() {
 		}
 	}()
 
-	// Initialize Redis
+	// Initialize Redis. Without it the service starts degraded and keeps
+	// trying to reconnect.
 	redisClient, err := config.InitRedis(cfg)
 	if err != nil {
-		log.Fatal("Failed to connect to Redis", zap.Error(err))
+		log.Fatal("Failed to initialize Redis", zap.Error(err))
 	}
 	defer redisClient.Close()
+	metrics.RegisterRedisPool(redisClient)
+	redisGuard, err := redisguard.New(
+		redisClient,
+		cfg.Redis.Policies,
+		time.Duration(cfg.Redis.ReconnectInterval)*time.Second,
+	)
+	if err != nil {
+		log.Fatal("Invalid Redis degradation policy", zap.Error(err))
+	}
+	if err := redisGuard.Connect(ctx); err != nil {
+		log.Warn("Redis unavailable, starting in degraded mode", zap.Error(err))
+	}
+
+	// Load the keyring for encrypted user fields
+	keyring, err := fieldcrypt.LoadKeyring(cfg.Encryption.KeyringFile)
+	if err != nil {
+		log.Fatal("Failed to load encryption keyring", zap.Error(err))
+	}
+
+	// Initialize services
+	svc, err := services.NewRegistry(cfg, mongoClient, redisGuard, keyring)
+	if err != nil {
+		log.Fatal("Failed to initialize services", zap.Error(err))
+	}
+	if err := svc.User.EnsureIndexes(ctx); err != nil {
+		log.Fatal("Failed to create user indexes", zap.Error(err))
+	}
+	if err := svc.Handle.EnsureIndexes(ctx); err != nil {
+		log.Fatal("Failed to create handle indexes", zap.Error(err))
+	}
+	if err := svc.Consent.EnsureIndexes(ctx); err != nil {
+		log.Fatal("Failed to create policy indexes", zap.Error(err))
+	}
+
+	// Start background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go svc.Account.RunPurgeLoop(jobsCtx)
+	go svc.Export.RunExportWorker(jobsCtx)
+	go svc.Keys.RunReencryptionLoop(jobsCtx)
+	go redisGuard.Run(jobsCtx)
 
 	// Setup router
-	r := router.NewRouter(cfg, mongoClient, redisClient)
+	r := router.NewRouter(cfg, mongoClient, redisClient, svc)
 
 	// Create server
 	srv := &http.Server{
@@ -72,21 +168,57 @@ func main() {
 
 	// Start server in a goroutine
 	go func() {
-		log.Info("Starting server", zap.String("addr", srv.Addr))
+		log.Info("Starting server",
+			zap.String("addr", srv.Addr),
+			zap.String("version", version.Version),
+			zap.String("commit", version.Commit),
+		)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatal("Server failed to start", zap.Error(err))
 		}
 	}()
 
+	// Serve metrics on the admin port when one is configured
+	var adminSrv *http.Server
+	if cfg.Metrics.Enabled && cfg.Metrics.AdminPort != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		adminSrv = &http.Server{
+			Addr:    ":" + cfg.Metrics.AdminPort,
+			Handler: mux,
+		}
+
+		go func() {
+			log.Info("Starting admin server", zap.String("addr", adminSrv.Addr))
+			if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal("Admin server failed to start", zap.Error(err))
+			}
+		}()
+	}
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Info("Shutting down server...")
 
+	// Fail readiness first and give the orchestrator time to notice before
+	// connections are refused
+	r.Health().ShutDown()
+	if delay := time.Duration(cfg.Health.ShutdownDelay) * time.Second; delay > 0 {
+		log.Info("Waiting for traffic to drain", zap.Duration("delay", delay))
+		time.Sleep(delay)
+	}
+	stopJobs()
+
 	// Shutdown with timeout
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(shutdownCtx); err != nil {
+			log.Error("Admin server forced to shutdown", zap.Error(err))
+		}
+	}
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatal("Server forced to shutdown", zap.Error(err))
 	}
@@ -15,6 +15,8 @@ type Config struct {
 		Host        string `mapstructure:"host"`
 		Port        string `mapstructure:"port"`
 		Environment string `mapstructure:"environment"`
+		PublicURL   string `mapstructure:"public_url"`
+		WebAppURL   string `mapstructure:"web_app_url"`
 	} `mapstructure:"server"`
 
 	MongoDB struct {
@@ -24,13 +26,128 @@ type Config struct {
 	} `mapstructure:"mongodb"`
 
 	Redis struct {
-		URI string `mapstructure:"uri"`
+		URI               string            `mapstructure:"uri"`
+		ReconnectInterval int               `mapstructure:"reconnect_interval"`
+		Policies          map[string]string `mapstructure:"policies"`
 	} `mapstructure:"redis"`
 
+	RateLimit struct {
+		Requests int `mapstructure:"requests"`
+		Window   int `mapstructure:"window"`
+	} `mapstructure:"rate_limit"`
+
 	JWT struct {
 		Secret     string `mapstructure:"secret"`
 		Expiration int    `mapstructure:"expiration"`
 	} `mapstructure:"jwt"`
+
+	OAuth struct {
+		ClientTokenExpiration int `mapstructure:"client_token_expiration"`
+	} `mapstructure:"oauth"`
+
+	DPoP struct {
+		ProofMaxAge int `mapstructure:"proof_max_age"`
+	} `mapstructure:"dpop"`
+
+	QRLogin struct {
+		TTL         int `mapstructure:"ttl"`
+		PollTimeout int `mapstructure:"poll_timeout"`
+	} `mapstructure:"qr_login"`
+
+	AccountDeletion struct {
+		GracePeriodDays int `mapstructure:"grace_period_days"`
+		PurgeInterval   int `mapstructure:"purge_interval"`
+	} `mapstructure:"account_deletion"`
+
+	DataExport struct {
+		Directory      string `mapstructure:"directory"`
+		SigningKey     string `mapstructure:"signing_key"`
+		LinkTTL        int    `mapstructure:"link_ttl"`
+		RetentionHours int    `mapstructure:"retention_hours"`
+		PollInterval   int    `mapstructure:"poll_interval"`
+	} `mapstructure:"data_export"`
+
+	Encryption struct {
+		KeyringFile        string `mapstructure:"keyring_file"`
+		ReencryptInterval  int    `mapstructure:"reencrypt_interval"`
+		ReencryptBatchSize int    `mapstructure:"reencrypt_batch_size"`
+	} `mapstructure:"encryption"`
+
+	SMTP struct {
+		Host     string `mapstructure:"host"`
+		Port     int    `mapstructure:"port"`
+		Username string `mapstructure:"username"`
+		Password string `mapstructure:"password"`
+		From     string `mapstructure:"from"`
+	} `mapstructure:"smtp"`
+
+	EmailVerification struct {
+		TokenTTL int `mapstructure:"token_ttl"`
+	} `mapstructure:"email_verification"`
+
+	Handles struct {
+		ReservedFile       string `mapstructure:"reserved_file"`
+		ProfanityFile      string `mapstructure:"profanity_file"`
+		ChangeCooldownDays int    `mapstructure:"change_cooldown_days"`
+		RedirectDays       int    `mapstructure:"redirect_days"`
+	} `mapstructure:"handles"`
+
+	Cache struct {
+		DefaultTTL      int            `mapstructure:"default_ttl"`
+		LockTimeout     int            `mapstructure:"lock_timeout"`
+		RouteTTLs       map[string]int `mapstructure:"route_ttls"`
+		UserTTL         int            `mapstructure:"user_ttl"`
+		UserNegativeTTL int            `mapstructure:"user_negative_ttl"`
+	} `mapstructure:"cache"`
+
+	PasswordHashing struct {
+		Concurrency int `mapstructure:"concurrency"`
+		QueueDepth  int `mapstructure:"queue_depth"`
+	} `mapstructure:"password_hashing"`
+
+	Metrics struct {
+		Enabled   bool   `mapstructure:"enabled"`
+		AdminPort string `mapstructure:"admin_port"`
+	} `mapstructure:"metrics"`
+
+	Tracing struct {
+		Enabled      bool    `mapstructure:"enabled"`
+		Exporter     string  `mapstructure:"exporter"`
+		OTLPEndpoint string  `mapstructure:"otlp_endpoint"`
+		OTLPInsecure bool    `mapstructure:"otlp_insecure"`
+		SampleRatio  float64 `mapstructure:"sample_ratio"`
+		ServiceName  string  `mapstructure:"service_name"`
+	} `mapstructure:"tracing"`
+
+	Health struct {
+		CheckTimeout  int `mapstructure:"check_timeout"`
+		ShutdownDelay int `mapstructure:"shutdown_delay"`
+	} `mapstructure:"health"`
+
+	Logging struct {
+		// Level and outputs default by APP_ENV when empty
+		Level       string            `mapstructure:"level"`
+		Modules     map[string]string `mapstructure:"modules"`
+		Outputs     []string          `mapstructure:"outputs"`
+		ErrorOutput string            `mapstructure:"error_output"`
+		Rotation    struct {
+			MaxSizeMB     int  `mapstructure:"max_size_mb"`
+			MaxAgeDays    int  `mapstructure:"max_age_days"`
+			MaxBackups    int  `mapstructure:"max_backups"`
+			Compress      bool `mapstructure:"compress"`
+			IntervalHours int  `mapstructure:"interval_hours"`
+		} `mapstructure:"rotation"`
+		Sampling struct {
+			Initial    int `mapstructure:"initial"`
+			Thereafter int `mapstructure:"thereafter"`
+			Tick       int `mapstructure:"tick"`
+		} `mapstructure:"sampling"`
+		// Redaction rules keyed by APP_ENV
+		Redaction map[string]struct {
+			Fields   []string `mapstructure:"fields"`
+			Patterns []string `mapstructure:"patterns"`
+		} `mapstructure:"redaction"`
+	} `mapstructure:"logging"`
 }
 
 func LoadConfig(configPath string) (*Config, error) {
@@ -66,9 +183,13 @@ func LoadConfig(configPath string) (*Config, error) {
 func processConfigValues(v *viper.Viper) {
 	// Replace environment variables in specific configuration paths
 	configPaths := []string{
+		"server.web_app_url",
 		"mongodb.uri",
 		"redis.uri",
 		"jwt.secret",
+		"data_export.signing_key",
+		"encryption.keyring_file",
+		"smtp.password",
 	}
 
 	for _, path := range configPaths {
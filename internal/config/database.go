@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/metrics"
 )
 
 func InitMongoDB(ctx context.Context, cfg *Config) (*mongo.Client, error) {
@@ -15,7 +19,9 @@ func InitMongoDB(ctx context.Context, cfg *Config) (*mongo.Client, error) {
 	opts := options.Client().
 		ApplyURI(cfg.MongoDB.URI).
 		SetServerAPIOptions(serverAPI).
-		SetConnectTimeout(10 * time.Second)
+		SetConnectTimeout(10 * time.Second).
+		SetPoolMonitor(metrics.MongoPoolMonitor()).
+		SetMonitor(otelmongo.NewMonitor())
 
 	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
@@ -33,13 +39,11 @@ func InitRedis(cfg *Config) (*redis.Client, error) {
 	rdb := redis.NewClient(&redis.Options{
 		Addr: cfg.Redis.URI,
 	})
-	fmt.Print(cfg.Redis.URI)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if _, err := rdb.Ping(ctx).Result(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	if err := redisotel.InstrumentTracing(rdb); err != nil {
+		return nil, fmt.Errorf("failed to instrument Redis: %w", err)
 	}
 
+	// Connectivity is checked by redisguard, so that the service can start
+	// while Redis is down
 	return rdb, nil
 }
\ No newline at end of file
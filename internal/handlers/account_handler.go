@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
+)
+
+type AccountHandler struct {
+	accountService *services.AccountService
+}
+
+func NewAccountHandler(accountService *services.AccountService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+	}
+}
+
+// RequestDeletion schedules the caller's account for deletion
+func (h *AccountHandler) RequestDeletion(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req models.AccountDeletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	scheduled, err := h.accountService.RequestDeletion(c.Request.Context(), userID, req.Password)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Account deletion request failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Account scheduled for deletion",
+		"purge_at": scheduled.PurgeAt,
+	})
+}
+
+// RestoreAccount cancels a pending deletion during the grace period
+func (h *AccountHandler) RestoreAccount(c *gin.Context) {
+	var req models.AccountRestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	if err := h.accountService.RestoreAccount(c.Request.Context(), &req); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Account restore failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account restored. You can log in again.",
+	})
+}
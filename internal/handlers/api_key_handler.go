@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateKey issues a personal API key and returns its secret once
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req models.APIKeyCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Invalid API key input", zap.Error(err))
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Validation failed", zap.Error(err))
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	created, err := h.apiKeyService.CreateKey(c.Request.Context(), userID, &req)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("API key creation failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, created)
+}
+
+// ListKeys returns the caller's API keys without their secrets
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	keys, err := h.apiKeyService.ListKeys(c.Request.Context(), userID)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Failed to list API keys", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": keys,
+	})
+}
+
+// RevokeKey disables one of the caller's API keys
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	keyID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(http.StatusBadRequest, "Invalid API key ID"))
+		return
+	}
+
+	if err := h.apiKeyService.RevokeKey(c.Request.Context(), userID, keyID); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Failed to revoke API key", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key revoked",
+	})
+}
+
+// currentUserID returns the authenticated user's ID set by AuthMiddleware
+func currentUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		return primitive.NilObjectID, false
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	return objectID, true
+}
@@ -19,13 +19,15 @@ import (
 
 type AuthHandler struct {
 	authService *services.AuthService
+	dpopService *services.DPoPService
 	cfg         *config.Config
 	redisClient *redis.Client
 }
 
-func NewAuthHandler(authService *services.AuthService, cfg *config.Config, redisClient *redis.Client) *AuthHandler {
+func NewAuthHandler(authService *services.AuthService, dpopService *services.DPoPService, cfg *config.Config, redisClient *redis.Client) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		dpopService: dpopService,
 		cfg:         cfg,
 		redisClient: redisClient,
 	}
@@ -35,7 +37,7 @@ func NewAuthHandler(authService *services.AuthService, cfg *config.Config, redis
 func (h *AuthHandler) Register(c *gin.Context) {
 	var reg models.UserRegistration
 	if err := c.ShouldBindJSON(&reg); err != nil {
-		logger.GetLogger().Error("Invalid registration input", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Invalid registration input", zap.Error(err))
 		c.JSON(http.StatusBadRequest, errors.New(
 			http.StatusBadRequest,
 			"Invalid input",
@@ -46,7 +48,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	// Validate input
 	if err := utils.ValidateStruct(reg); err != nil {
-		logger.GetLogger().Error("Validation failed", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Validation failed", zap.Error(err))
 		c.JSON(http.StatusBadRequest, errors.New(
 			http.StatusBadRequest,
 			"Validation error",
@@ -56,9 +58,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Call service to register user
+	reg.IPAddress = c.ClientIP()
+	reg.UserAgent = c.Request.UserAgent()
 	user, err := h.authService.RegisterUser(c.Request.Context(), &reg)
 	if err != nil {
-		logger.GetLogger().Error("User registration failed", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("User registration failed", zap.Error(err))
 		c.JSON(errors.GetHTTPStatusCode(err), err)
 		return
 	}
@@ -73,7 +77,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var login models.UserLogin
 	if err := c.ShouldBindJSON(&login); err != nil {
-		logger.GetLogger().Error("Invalid login input", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Invalid login input", zap.Error(err))
 		c.JSON(http.StatusBadRequest, errors.New(
 			http.StatusBadRequest,
 			"Invalid input",
@@ -84,7 +88,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	// Validate input
 	if err := utils.ValidateStruct(login); err != nil {
-		logger.GetLogger().Error("Validation failed", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Validation failed", zap.Error(err))
 		c.JSON(http.StatusBadRequest, errors.New(
 			http.StatusBadRequest,
 			"Validation error",
@@ -93,17 +97,33 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Bind the token to the client's key when a DPoP proof is presented
+	jkt, ok := verifyDPoP(c, h.dpopService)
+	if !ok {
+		return
+	}
+
 	// Authenticate user
-	token, err := h.authService.LoginUser(c.Request.Context(), &login)
+	login.IPAddress = c.ClientIP()
+	login.UserAgent = c.Request.UserAgent()
+	result, err := h.authService.LoginUser(c.Request.Context(), &login, jkt)
 	if err != nil {
-		logger.GetLogger().Error("User authentication failed", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("User authentication failed", zap.Error(err))
 		c.JSON(errors.GetHTTPStatusCode(err), err)
 		return
 	}
 
+	tokenType := "Bearer"
+	if jkt != "" {
+		tokenType = "DPoP"
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
+		"message":          "Login successful",
+		"token":            result.Token,
+		"token_type":       tokenType,
+		"consent_required": result.ConsentRequired,
+		"pending_policies": result.PendingPolicies,
 	})
 }
 
@@ -111,7 +131,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 func (h *AuthHandler) PasswordRecovery(c *gin.Context) {
 	var req models.PasswordRecoveryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logger.GetLogger().Error("Invalid password recovery input", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Invalid password recovery input", zap.Error(err))
 		c.JSON(http.StatusBadRequest, errors.New(
 			http.StatusBadRequest,
 			"Invalid input",
@@ -122,7 +142,7 @@ func (h *AuthHandler) PasswordRecovery(c *gin.Context) {
 
 	// Validate input
 	if err := utils.ValidateStruct(req); err != nil {
-		logger.GetLogger().Error("Validation failed", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Validation failed", zap.Error(err))
 		c.JSON(http.StatusBadRequest, errors.New(
 			http.StatusBadRequest,
 			"Validation error",
@@ -134,7 +154,7 @@ func (h *AuthHandler) PasswordRecovery(c *gin.Context) {
 	// Initiate password recovery
 	err := h.authService.PasswordRecovery(c.Request.Context(), &req)
 	if err != nil {
-		logger.GetLogger().Error("Password recovery failed", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Password recovery failed", zap.Error(err))
 		c.JSON(errors.GetHTTPStatusCode(err), err)
 		return
 	}
@@ -148,7 +168,7 @@ func (h *AuthHandler) PasswordRecovery(c *gin.Context) {
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var req models.ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logger.GetLogger().Error("Invalid reset password input", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Invalid reset password input", zap.Error(err))
 		c.JSON(http.StatusBadRequest, errors.New(
 			http.StatusBadRequest,
 			"Invalid input",
@@ -159,7 +179,7 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 
 	// Validate input
 	if err := utils.ValidateStruct(req); err != nil {
-		logger.GetLogger().Error("Validation failed", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Validation failed", zap.Error(err))
 		c.JSON(http.StatusBadRequest, errors.New(
 			http.StatusBadRequest,
 			"Validation error",
@@ -171,7 +191,7 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	// Perform password reset
 	err := h.authService.ResetPassword(c.Request.Context(), &req)
 	if err != nil {
-		logger.GetLogger().Error("Password reset failed", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Password reset failed", zap.Error(err))
 		c.JSON(errors.GetHTTPStatusCode(err), err)
 		return
 	}
@@ -197,11 +217,12 @@ func (h *AuthHandler) Profile(c *gin.Context) {
 
 	user, err := h.authService.GetUserByID(c.Request.Context(), objectID)
 	if err != nil {
-		logger.GetLogger().Error("Fetching user profile failed", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Fetching user profile failed", zap.Error(err))
 		c.JSON(errors.GetHTTPStatusCode(err), err)
 		return
 	}
 
+	setETag(c, user.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"user": user,
 	})
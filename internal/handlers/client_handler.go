@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
+)
+
+type ClientHandler struct {
+	clientService *services.ClientService
+}
+
+func NewClientHandler(clientService *services.ClientService) *ClientHandler {
+	return &ClientHandler{
+		clientService: clientService,
+	}
+}
+
+// CreateClient registers a service client and returns its secret once
+func (h *ClientHandler) CreateClient(c *gin.Context) {
+	var reg models.ClientRegistration
+	if err := c.ShouldBindJSON(&reg); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Invalid client registration input", zap.Error(err))
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(reg); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Validation failed", zap.Error(err))
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	credentials, err := h.clientService.CreateClient(c.Request.Context(), &reg)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Client registration failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, credentials)
+}
+
+// ListClients returns every registered service client
+func (h *ClientHandler) ListClients(c *gin.Context) {
+	clients, err := h.clientService.ListClients(c.Request.Context())
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Failed to list clients", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clients": clients,
+	})
+}
+
+// DisableClient prevents a service client from authenticating
+func (h *ClientHandler) DisableClient(c *gin.Context) {
+	if err := h.clientService.DisableClient(c.Request.Context(), c.Param("client_id")); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Failed to disable client", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Client disabled",
+	})
+}
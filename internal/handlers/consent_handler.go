@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
+)
+
+type ConsentHandler struct {
+	consentService *services.ConsentService
+}
+
+func NewConsentHandler(consentService *services.ConsentService) *ConsentHandler {
+	return &ConsentHandler{
+		consentService: consentService,
+	}
+}
+
+// CurrentPolicies lists the policy versions users must accept
+func (h *ConsentHandler) CurrentPolicies(c *gin.Context) {
+	policies, err := h.consentService.CurrentPolicies(c.Request.Context())
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Failed to get current policies", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policies": policies,
+	})
+}
+
+// Accept records the caller's acceptance of a policy version
+func (h *ConsentHandler) Accept(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req models.ConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	consent, err := h.consentService.RecordConsent(
+		c.Request.Context(),
+		userID,
+		req.PolicyType,
+		req.Version,
+		c.ClientIP(),
+		c.Request.UserAgent(),
+	)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Recording consent failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"consent": consent,
+	})
+}
+
+// ListMine returns the caller's consent history and any policies still pending
+func (h *ConsentHandler) ListMine(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	consents, err := h.consentService.ListUserConsents(c.Request.Context(), userID)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Failed to list consents", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	pending, err := h.consentService.PendingPolicies(c.Request.Context(), userID)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Failed to get pending policies", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"consents":         consents,
+		"pending_policies": pending,
+	})
+}
+
+// PublishPolicy registers a new policy version
+func (h *ConsentHandler) PublishPolicy(c *gin.Context) {
+	var req models.PolicyPublishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	policy, err := h.consentService.PublishPolicy(c.Request.Context(), &req)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Publishing policy failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"policy": policy,
+	})
+}
+
+// ListPolicies returns every registered policy version
+func (h *ConsentHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.consentService.ListPolicies(c.Request.Context())
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Failed to list policies", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policies": policies,
+	})
+}
+
+// Coverage reports how many users accepted each current policy version
+func (h *ConsentHandler) Coverage(c *gin.Context) {
+	coverage, err := h.consentService.Coverage(c.Request.Context())
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Failed to compute consent coverage", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"coverage": coverage,
+	})
+}
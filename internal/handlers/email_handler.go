@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
+)
+
+type EmailHandler struct {
+	emailService *services.EmailService
+}
+
+func NewEmailHandler(emailService *services.EmailService) *EmailHandler {
+	return &EmailHandler{
+		emailService: emailService,
+	}
+}
+
+// RequestVerification sends a verification link to a new email address
+func (h *EmailHandler) RequestVerification(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req models.EmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	if err := h.emailService.RequestVerification(c.Request.Context(), userID, req.Email); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Email verification request failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Verification email sent. Follow the link in it to confirm the address.",
+	})
+}
+
+// Verify confirms an email address with the token from the verification link
+func (h *EmailHandler) Verify(c *gin.Context) {
+	var req models.EmailVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	user, err := h.emailService.Verify(c.Request.Context(), req.Token)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Email verification failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email address verified",
+		"email":   user.Email,
+	})
+}
+
+// RemoveEmail removes the caller's email address
+func (h *EmailHandler) RemoveEmail(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	if err := h.emailService.RemoveEmail(c.Request.Context(), userID); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Removing email failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email address removed",
+	})
+}
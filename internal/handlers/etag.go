@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+)
+
+// setETag exposes a document version as a strong entity tag
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// requireIfMatch reads the version a write is conditioned on. It responds
+// with 428 when the header is missing and 412 when it is malformed. If-Match: *
+// yields -1, meaning any version is accepted.
+func requireIfMatch(c *gin.Context) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, errors.New(
+			http.StatusPreconditionRequired,
+			"Precondition required",
+			"Send the ETag from your last read in an If-Match header",
+		))
+		return 0, false
+	}
+
+	if header == "*" {
+		return -1, true
+	}
+
+	// Weak tags are not valid for If-Match comparisons (RFC 9110 section 13.1.1)
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		c.JSON(http.StatusPreconditionFailed, errors.New(http.StatusPreconditionFailed, "Invalid If-Match header"))
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusPreconditionFailed, errors.New(http.StatusPreconditionFailed, "Invalid If-Match header"))
+		return 0, false
+	}
+
+	return version, true
+}
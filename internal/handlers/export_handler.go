@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
+)
+
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// RequestMyExport queues an export of the caller's personal data
+func (h *ExportHandler) RequestMyExport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	h.requestExport(c, userID, userID.Hex())
+}
+
+// GetMyExport reports the status of one of the caller's exports
+func (h *ExportHandler) GetMyExport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	h.getExport(c, &userID)
+}
+
+// RequestUserExport queues an export of any user's data on an admin's behalf
+func (h *ExportHandler) RequestUserExport(c *gin.Context) {
+	adminID, _ := c.Get("userID")
+
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(http.StatusBadRequest, "Invalid user ID"))
+		return
+	}
+
+	h.requestExport(c, userID, adminID.(string))
+}
+
+// GetUserExport reports the status of any export
+func (h *ExportHandler) GetUserExport(c *gin.Context) {
+	h.getExport(c, nil)
+}
+
+// Download serves an export archive through a signed, expiring link
+func (h *ExportHandler) Download(c *gin.Context) {
+	path, err := h.exportService.OpenDownload(
+		c.Request.Context(),
+		c.Param("id"),
+		c.Query("expires"),
+		c.Query("signature"),
+	)
+	if err != nil {
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(path, "greeneye-data-export.zip")
+}
+
+func (h *ExportHandler) requestExport(c *gin.Context, userID primitive.ObjectID, actorID string) {
+	export, err := h.exportService.RequestExport(c.Request.Context(), userID, actorID)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Data export request failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"export": export,
+	})
+}
+
+func (h *ExportHandler) getExport(c *gin.Context, userID *primitive.ObjectID) {
+	exportID, err := primitive.ObjectIDFromHex(c.Param("export_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(http.StatusBadRequest, "Invalid export ID"))
+		return
+	}
+
+	export, err := h.exportService.GetExport(c.Request.Context(), exportID, userID)
+	if err != nil {
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"export": export,
+	})
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
+)
+
+type HandleHandler struct {
+	handleService *services.HandleService
+}
+
+func NewHandleHandler(handleService *services.HandleService) *HandleHandler {
+	return &HandleHandler{
+		handleService: handleService,
+	}
+}
+
+// CheckAvailability reports whether a handle can be claimed
+func (h *HandleHandler) CheckAvailability(c *gin.Context) {
+	availability, err := h.handleService.CheckAvailability(c.Request.Context(), c.Param("handle"), primitive.NilObjectID)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Handle availability check failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
+
+// Resolve returns the public profile of a handle, redirecting recently
+// released handles to their owner's current one
+func (h *HandleHandler) Resolve(c *gin.Context) {
+	profile, current, err := h.handleService.Resolve(c.Request.Context(), c.Param("handle"))
+	if err != nil {
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	if current != "" {
+		c.Redirect(http.StatusFound, "/api/handles/"+url.PathEscape(current))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profile": profile,
+	})
+}
+
+// ChangeHandle sets the caller's handle
+func (h *HandleHandler) ChangeHandle(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req models.HandleChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	user, err := h.handleService.ChangeHandle(c.Request.Context(), userID, req.Handle)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Changing handle failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	setETag(c, user.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"user": user,
+	})
+}
+
+// ClearHandle removes the caller's handle
+func (h *HandleHandler) ClearHandle(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	if err := h.handleService.ClearHandle(c.Request.Context(), userID); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Clearing handle failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Handle removed",
+	})
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+)
+
+type LogLevelHandler struct{}
+
+func NewLogLevelHandler() *LogLevelHandler {
+	return &LogLevelHandler{}
+}
+
+// GetLevels returns the global log level and the per-module overrides
+func (h *LogLevelHandler) GetLevels(c *gin.Context) {
+	c.JSON(http.StatusOK, logger.GetLevels())
+}
+
+// SetLevel changes the global or a module's log level until the next restart
+func (h *LogLevelHandler) SetLevel(c *gin.Context) {
+	actorID, _ := c.Get("userID")
+
+	var req models.LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	if err := logger.SetLevel(req.Module, req.Level); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(http.StatusBadRequest, "Invalid log level", err.Error()))
+		return
+	}
+
+	logger.WithContext(c.Request.Context()).Warn("Log level changed",
+		zap.String("module", req.Module),
+		zap.String("level", req.Level),
+		zap.Any("actor_id", actorID),
+	)
+
+	c.JSON(http.StatusOK, logger.GetLevels())
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
+)
+
+type QRLoginHandler struct {
+	qrLoginService *services.QRLoginService
+	dpopService    *services.DPoPService
+}
+
+func NewQRLoginHandler(qrLoginService *services.QRLoginService, dpopService *services.DPoPService) *QRLoginHandler {
+	return &QRLoginHandler{
+		qrLoginService: qrLoginService,
+		dpopService:    dpopService,
+	}
+}
+
+// CreateSession starts a QR code login for a web client
+func (h *QRLoginHandler) CreateSession(c *gin.Context) {
+	created, err := h.qrLoginService.CreateSession(c.Request.Context(), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("QR login creation failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetSession shows the approving device where a login request came from
+func (h *QRLoginHandler) GetSession(c *gin.Context) {
+	details, err := h.qrLoginService.GetSession(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+// Approve logs the web client in as the authenticated user
+func (h *QRLoginHandler) Approve(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	if err := h.qrLoginService.Approve(c.Request.Context(), c.Param("id"), userID); err != nil {
+		logger.WithContext(c.Request.Context()).Error("QR login approval failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login approved",
+	})
+}
+
+// Deny rejects a pending login request as the authenticated user
+func (h *QRLoginHandler) Deny(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	if err := h.qrLoginService.Deny(c.Request.Context(), c.Param("id"), userID); err != nil {
+		logger.WithContext(c.Request.Context()).Error("QR login denial failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login denied",
+	})
+}
+
+// Poll long-polls until the login is answered and returns the access token
+// to the web client that created the session
+func (h *QRLoginHandler) Poll(c *gin.Context) {
+	var req models.QRLoginPollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	// Bind the token to the web client's key when a DPoP proof is presented
+	jkt, ok := verifyDPoP(c, h.dpopService)
+	if !ok {
+		return
+	}
+
+	token, err := h.qrLoginService.WaitForApproval(c.Request.Context(), c.Param("id"), req.Secret, jkt)
+	if err != nil {
+		if c.Request.Context().Err() != nil {
+			return
+		}
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	if token == "" {
+		c.JSON(http.StatusAccepted, gin.H{
+			"status": models.QRLoginPending,
+		})
+		return
+	}
+
+	tokenType := "Bearer"
+	if jkt != "" {
+		tokenType = "DPoP"
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Login successful",
+		"token":      token,
+		"token_type": tokenType,
+	})
+}
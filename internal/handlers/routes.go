@@ -2,39 +2,143 @@ package handlers
 
 import (
 	"github.com/greeneye-foundation/greeneye-be-user/internal/middleware"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/cache"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
 	"github.com/redis/go-redis/v9"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func SetupRoutes(router *gin.Engine, db *mongo.Client, cfg *config.Config, redis *redis.Client) {
-	// Initialize services
-	userService := services.NewUserService(db, cfg.MongoDB.Database)
-	authService := services.NewAuthService(userService, cfg, redis)
-
+func SetupRoutes(router *gin.Engine, svc *services.Registry, cfg *config.Config, redis *redis.Client) {
 	// Initialize handlers
-	authHandler := NewAuthHandler(authService, cfg, redis)
-	userHandler := NewUserHandler(userService)
+	authHandler := NewAuthHandler(svc.Auth, svc.DPoP, cfg, redis)
+	userHandler := NewUserHandler(svc.User)
+	tokenHandler := NewTokenHandler(svc.Token, svc.Client, svc.DPoP)
+	clientHandler := NewClientHandler(svc.Client)
+	apiKeyHandler := NewAPIKeyHandler(svc.APIKey)
+	qrLoginHandler := NewQRLoginHandler(svc.QRLogin, svc.DPoP)
+	accountHandler := NewAccountHandler(svc.Account)
+	exportHandler := NewExportHandler(svc.Export)
+	consentHandler := NewConsentHandler(svc.Consent)
+	statusHandler := NewStatusHandler(svc.Status)
+	emailHandler := NewEmailHandler(svc.Email)
+	handleHandler := NewHandleHandler(svc.Handle)
+	logLevelHandler := NewLogLevelHandler()
+
+	authMiddleware := middleware.AuthMiddleware(svc.Token, svc.APIKey, svc.DPoP)
+	responseCache := middleware.NewResponseCache(svc.Cache, svc.Redis, cfg)
 
 	// API group
 	api := router.Group("/api")
+	if cfg.RateLimit.Requests > 0 {
+		api.Use(middleware.RateLimit(svc.Redis, cfg))
+	}
 	{
 		// Auth routes
 		auth := api.Group("/auth")
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/restore-account", accountHandler.RestoreAccount)
+			auth.POST("/password-recovery", authHandler.PasswordRecovery)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.POST("/verify-email", emailHandler.Verify)
+		}
+
+		// Policies users must accept to sign up
+		api.GET("/policies", consentHandler.CurrentPolicies)
+
+		// Cross-device QR code login: the web client creates and polls a
+		// session, the signed-in mobile app approves it
+		qrLogin := api.Group("/auth/qr-login")
+		{
+			qrLogin.POST("", qrLoginHandler.CreateSession)
+			qrLogin.POST("/:id/poll", qrLoginHandler.Poll)
+			qrLogin.GET("/:id", authMiddleware, middleware.RequireUserSession(), qrLoginHandler.GetSession)
+			qrLogin.POST("/:id/approve", authMiddleware, middleware.RequireUserSession(), qrLoginHandler.Approve)
+			qrLogin.POST("/:id/deny", authMiddleware, middleware.RequireUserSession(), qrLoginHandler.Deny)
+		}
+
+		// OAuth routes for downstream services
+		oauth := api.Group("/oauth")
+		oauth.Use(middleware.ClientAuthMiddleware(svc.Client))
+		{
+			oauth.POST("/token", tokenHandler.Token)
+			oauth.POST("/introspect", tokenHandler.Introspect)
+			oauth.POST("/revoke", tokenHandler.Revoke)
 		}
 
 		// User routes
 		users := api.Group("/users")
-		users.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+		users.Use(authMiddleware)
 		{
-			users.GET("/", middleware.CacheMiddleware(redis), userHandler.GetUsers)
+			users.GET("/", middleware.RequireServiceClientOrRoles("admin"), middleware.RequireScopes(models.ScopeUsersRead), responseCache.CacheMiddleware(middleware.StaticTag(cache.UsersTag)), userHandler.GetUsers)
+			users.GET("/:id", middleware.RequireScopes(models.ScopeUsersRead), responseCache.CacheMiddleware(middleware.UserParamTag("id")), userHandler.GetProfile)
+			// Other users only see the public profile, see UserHandler.GetProfile
 			// Add other user routes
 		}
+
+		// Current user routes
+		me := api.Group("/me")
+		me.Use(authMiddleware)
+		{
+			me.GET("", middleware.RequireScopes(models.ScopeUsersRead), responseCache.CacheMiddleware(middleware.CallerTag()), authHandler.Profile)
+			me.PATCH("", middleware.RequireScopes(models.ScopeUsersWrite), userHandler.UpdateMe)
+			me.PUT("/email", middleware.RequireUserSession(), emailHandler.RequestVerification)
+			me.DELETE("/email", middleware.RequireUserSession(), emailHandler.RemoveEmail)
+			me.PUT("/handle", middleware.RequireUserSession(), handleHandler.ChangeHandle)
+			me.DELETE("/handle", middleware.RequireUserSession(), handleHandler.ClearHandle)
+			me.POST("/deletion", middleware.RequireUserSession(), accountHandler.RequestDeletion)
+			me.POST("/exports", middleware.RequireUserSession(), exportHandler.RequestMyExport)
+			me.GET("/exports/:export_id", middleware.RequireUserSession(), exportHandler.GetMyExport)
+			me.GET("/consents", middleware.RequireUserSession(), consentHandler.ListMine)
+			me.POST("/consents", middleware.RequireUserSession(), consentHandler.Accept)
+		}
+
+		// Public handle lookups for other services
+		handles := api.Group("/handles")
+		{
+			handles.GET("/:handle", handleHandler.Resolve)
+			handles.GET("/:handle/availability", handleHandler.CheckAvailability)
+		}
+
+		// Export downloads are authorized by the signed link itself
+		api.GET("/exports/:id/download", exportHandler.Download)
+
+		// Personal API key routes
+		apiKeys := api.Group("/api-keys")
+		apiKeys.Use(authMiddleware, middleware.RequireUserSession())
+		{
+			apiKeys.POST("", apiKeyHandler.CreateKey)
+			apiKeys.GET("", apiKeyHandler.ListKeys)
+			apiKeys.DELETE("/:id", apiKeyHandler.RevokeKey)
+		}
+
+		// Admin routes. Staff roles only count for interactive sessions, so
+		// API keys and service clients can never reach them.
+		admin := api.Group("/admin")
+		admin.Use(authMiddleware, middleware.RequireUserSession(), middleware.RequireRoles("admin"))
+		{
+			admin.POST("/clients", clientHandler.CreateClient)
+			admin.GET("/clients", clientHandler.ListClients)
+			admin.DELETE("/clients/:client_id", clientHandler.DisableClient)
+			admin.POST("/users/:id/exports", exportHandler.RequestUserExport)
+			admin.GET("/exports/:export_id", exportHandler.GetUserExport)
+			admin.POST("/policies", consentHandler.PublishPolicy)
+			admin.GET("/policies", consentHandler.ListPolicies)
+			admin.GET("/consents/coverage", consentHandler.Coverage)
+			admin.GET("/log-levels", logLevelHandler.GetLevels)
+			admin.PUT("/log-levels", logLevelHandler.SetLevel)
+		}
+
+		// Support routes for handling abusive accounts
+		support := api.Group("/support")
+		support.Use(authMiddleware, middleware.RequireUserSession(), middleware.RequireRoles("admin", "support"))
+		{
+			support.PUT("/users/:id/status", statusHandler.ChangeStatus)
+			support.GET("/users/:id/status-history", statusHandler.History)
+		}
 	}
 }
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
+)
+
+type StatusHandler struct {
+	statusService *services.StatusService
+}
+
+func NewStatusHandler(statusService *services.StatusService) *StatusHandler {
+	return &StatusHandler{
+		statusService: statusService,
+	}
+}
+
+// ChangeStatus suspends, bans or reactivates a user
+func (h *StatusHandler) ChangeStatus(c *gin.Context) {
+	actorID, _ := c.Get("userID")
+
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(http.StatusBadRequest, "Invalid user ID"))
+		return
+	}
+
+	var req models.StatusChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Validation error",
+			err.Error(),
+		))
+		return
+	}
+
+	user, err := h.statusService.ChangeStatus(c.Request.Context(), userID, &req, actorID.(string))
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Changing user status failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": user,
+	})
+}
+
+// History returns the status changes of a user for support staff
+func (h *StatusHandler) History(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errors.New(http.StatusBadRequest, "Invalid user ID"))
+		return
+	}
+
+	history, err := h.statusService.ListHistory(c.Request.Context(), userID)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Failed to list status history", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history": history,
+	})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
+)
+
+type TokenHandler struct {
+	tokenService  *services.TokenService
+	clientService *services.ClientService
+	dpopService   *services.DPoPService
+}
+
+func NewTokenHandler(tokenService *services.TokenService, clientService *services.ClientService, dpopService *services.DPoPService) *TokenHandler {
+	return &TokenHandler{
+		tokenService:  tokenService,
+		clientService: clientService,
+		dpopService:   dpopService,
+	}
+}
+
+// Token issues scoped access tokens through the client credentials grant
+func (h *TokenHandler) Token(c *gin.Context) {
+	var req models.ClientTokenRequest
+	if err := c.ShouldBind(&req); err != nil || utils.ValidateStruct(req) != nil {
+		c.JSON(http.StatusBadRequest, models.OAuthError{
+			Error:            "invalid_request",
+			ErrorDescription: "The grant_type parameter is required",
+		})
+		return
+	}
+
+	if req.GrantType != "client_credentials" {
+		c.JSON(http.StatusBadRequest, models.OAuthError{
+			Error:            "unsupported_grant_type",
+			ErrorDescription: "Only the client_credentials grant is supported",
+		})
+		return
+	}
+
+	client := c.MustGet("client").(*models.ServiceClient)
+	scopes, err := h.clientService.ResolveScopes(client, req.Scope)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.OAuthError{
+			Error:            "invalid_scope",
+			ErrorDescription: err.Error(),
+		})
+		return
+	}
+
+	// Bind the token to the client's key when a DPoP proof is presented
+	jkt, ok := verifyDPoP(c, h.dpopService)
+	if !ok {
+		return
+	}
+
+	token, err := h.tokenService.IssueClientToken(client, scopes, jkt)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Client token issuance failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.OAuthError{
+			Error: "server_error",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, token)
+}
+
+// Introspect reports whether a token is active (RFC 7662)
+func (h *TokenHandler) Introspect(c *gin.Context) {
+	var req models.TokenIntrospectionRequest
+	if err := c.ShouldBind(&req); err != nil || utils.ValidateStruct(req) != nil {
+		c.JSON(http.StatusBadRequest, models.OAuthError{
+			Error:            "invalid_request",
+			ErrorDescription: "The token parameter is required",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, h.tokenService.Introspect(c.Request.Context(), req.Token))
+}
+
+// Revoke invalidates a token (RFC 7009). Unknown or invalid tokens are
+// reported as revoked so callers cannot probe token validity.
+func (h *TokenHandler) Revoke(c *gin.Context) {
+	var req models.TokenRevocationRequest
+	if err := c.ShouldBind(&req); err != nil || utils.ValidateStruct(req) != nil {
+		c.JSON(http.StatusBadRequest, models.OAuthError{
+			Error:            "invalid_request",
+			ErrorDescription: "The token parameter is required",
+		})
+		return
+	}
+
+	// Clients may revoke their own tokens and user tokens presented to them;
+	// others are ignored without telling the caller
+	if err := h.tokenService.RevokeToken(c.Request.Context(), req.Token, c.GetString("clientID")); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Token revocation failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, models.OAuthError{
+			Error:            "temporarily_unavailable",
+			ErrorDescription: "Token revocation is temporarily unavailable",
+		})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// verifyDPoP checks the optional DPoP proof on a token request and returns
+// the thumbprint of the key the issued token should be bound to
+func verifyDPoP(c *gin.Context, dpopService *services.DPoPService) (string, bool) {
+	proof := c.GetHeader("DPoP")
+	if proof == "" {
+		return "", true
+	}
+
+	jkt, err := dpopService.VerifyProof(c.Request.Context(), proof, c.Request.Method, dpopService.RequestURL(c.Request), "")
+	if err == redisguard.ErrUnavailable {
+		c.JSON(http.StatusServiceUnavailable, models.OAuthError{
+			Error:            "temporarily_unavailable",
+			ErrorDescription: err.Error(),
+		})
+		return "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.OAuthError{
+			Error:            "invalid_dpop_proof",
+			ErrorDescription: err.Error(),
+		})
+		return "", false
+	}
+
+	return jkt, true
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/middleware"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
@@ -32,7 +36,7 @@ func NewUserHandler(userService *services.UserService) *UserHandler {
 // @Success 201 {object} models.User
 // @Router /users [post]
 func (h *UserHandler) RegisterUser(c *gin.Context) {
-	log := logger.GetLogger()
+	log := logger.WithContext(c.Request.Context())
 	var registration models.UserRegistration
 
 	// Bind and validate input
@@ -75,7 +79,7 @@ func (h *UserHandler) RegisterUser(c *gin.Context) {
 func (h *UserHandler) GetUsers(c *gin.Context) {
 	users, err := h.userService.GetUsers(c.Request.Context())
 	if err != nil {
-		logger.GetLogger().Error("Failed to get users", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Failed to get users", zap.Error(err))
 		c.JSON(errors.GetHTTPStatusCode(err), err)
 		return
 	}
@@ -95,11 +99,101 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 
 	user, err := h.userService.GetUserByID(c.Request.Context(), objectID)
 	if err != nil {
-		logger.GetLogger().Error("Failed to get user profile", zap.Error(err))
+		logger.WithContext(c.Request.Context()).Error("Failed to get user profile", zap.Error(err))
 		c.JSON(errors.GetHTTPStatusCode(err), err)
 		return
 	}
 
+	// Full profiles include contact details and moderation notes, so other
+	// users only get the public profile
+	if !canSeeFullProfile(c, objectID) {
+		c.JSON(http.StatusOK, gin.H{
+			"user": user.PublicProfile(),
+		})
+		return
+	}
+
+	setETag(c, user.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"user": user,
+	})
+}
+
+// canSeeFullProfile reports whether the caller may see every field of the
+// user: the user themselves, service clients and staff sessions
+func canSeeFullProfile(c *gin.Context, userID primitive.ObjectID) bool {
+	if callerID, ok := currentUserID(c); ok && callerID == userID {
+		return true
+	}
+	return middleware.IsServiceClient(c) || middleware.HasSessionRole(c, "admin", "support")
+}
+
+// UpdateMe applies a partial update to the caller's profile. The body is a
+// JSON Merge Patch (RFC 7396). When an update_mask query parameter is given,
+// only the listed fields are updated and listed fields missing from the body
+// are cleared.
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var body map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&body); err != nil {
+		logger.WithContext(c.Request.Context()).Error("Invalid profile update input", zap.Error(err))
+		c.JSON(http.StatusBadRequest, errors.New(
+			http.StatusBadRequest,
+			"Invalid input",
+			err.Error(),
+		))
+		return
+	}
+
+	fields := make([]string, 0, len(body))
+	if mask := c.Query("update_mask"); mask != "" {
+		for _, field := range strings.Split(mask, ",") {
+			fields = append(fields, strings.TrimSpace(field))
+		}
+	} else {
+		for field := range body {
+			fields = append(fields, field)
+		}
+	}
+
+	update := models.ProfileUpdate{}
+	for _, field := range fields {
+		raw, present := body[field]
+		if !present || bytes.Equal(raw, []byte("null")) {
+			update[field] = nil
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			c.JSON(http.StatusBadRequest, errors.New(
+				http.StatusBadRequest,
+				"Validation error",
+				field+": must be a string or null",
+			))
+			return
+		}
+		update[field] = &value
+	}
+
+	user, err := h.userService.UpdateProfile(c.Request.Context(), userID, expectedVersion, update)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error("Profile update failed", zap.Error(err))
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		return
+	}
+
+	setETag(c, user.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"user": user,
 	})
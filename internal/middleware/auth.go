@@ -5,12 +5,18 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
 
 	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// AuthMiddleware accepts a Bearer JWT, a DPoP-bound JWT or an ApiKey
+// credential in the Authorization header and sets the caller's identity on
+// the context
+func AuthMiddleware(tokenService *services.TokenService, apiKeyService *services.APIKeyService, dpopService *services.DPoPService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -20,46 +26,135 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		}
 
 		parts := strings.Fields(authHeader)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		if len(parts) != 2 {
 			c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
 			c.Abort()
 			return
 		}
 
-		tokenStr := parts[1]
-
-		// Parse token
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// Ensure the signing method is HMAC
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.ErrUnauthorized
-			}
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
+		switch scheme := strings.ToLower(parts[0]); scheme {
+		case "bearer", "dpop":
+			authenticateToken(c, tokenService, dpopService, scheme, parts[1])
+		case "apikey":
+			authenticateAPIKey(c, apiKeyService, parts[1])
+		default:
 			c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
 			c.Abort()
 			return
 		}
 
-		// Extract user ID from claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || claims["user_id"] == nil {
-			c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
-			c.Abort()
+		if c.IsAborted() {
 			return
 		}
 
-		userID, ok := claims["user_id"].(string)
-		if !ok {
+		// Tie the rest of the request's log lines to the caller
+		fields := []zap.Field{zap.String("auth_method", c.GetString("authMethod"))}
+		if userID := c.GetString("userID"); userID != "" {
+			fields = append(fields, zap.String("user_id", userID))
+		}
+		if clientID := c.GetString("clientID"); clientID != "" {
+			fields = append(fields, zap.String("client_id", clientID))
+		}
+		c.Request = c.Request.WithContext(logger.AddFields(c.Request.Context(), fields...))
+
+		c.Next()
+	}
+}
+
+func authenticateToken(c *gin.Context, tokenService *services.TokenService, dpopService *services.DPoPService, scheme, tokenStr string) {
+	// Parse token and check it has not been revoked and that its user may
+	// still sign in
+	claims, err := tokenService.ParseAccessToken(c.Request.Context(), tokenStr)
+	if err == services.ErrAccountInactive || err == redisguard.ErrUnavailable {
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		c.Abort()
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		c.Abort()
+		return
+	}
+
+	// Bound tokens must be presented with the DPoP scheme and a proof signed
+	// by the bound key; unbound tokens must not use the DPoP scheme
+	jkt := claims.KeyThumbprint()
+	if (jkt != "") != (scheme == "dpop") {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		c.Abort()
+		return
+	}
+	if jkt != "" {
+		proofJKT, err := dpopService.VerifyProof(
+			c.Request.Context(),
+			c.GetHeader("DPoP"),
+			c.Request.Method,
+			dpopService.RequestURL(c.Request),
+			tokenStr,
+		)
+		if err == redisguard.ErrUnavailable {
+			c.JSON(http.StatusServiceUnavailable, err)
+			c.Abort()
+			return
+		}
+		if err != nil || proofJKT != jkt {
+			c.Header("WWW-Authenticate", `DPoP error="invalid_dpop_proof"`)
 			c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
 			c.Abort()
 			return
 		}
+	}
+
+	// Tokens act either for a user or for a service client
+	if claims.UserID == "" && claims.ClientID == "" {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		c.Abort()
+		return
+	}
+
+	// Set identity to context
+	if claims.UserID != "" {
+		c.Set("userID", claims.UserID)
+		c.Set("roles", claims.Roles)
+	}
+	if claims.ClientID != "" {
+		c.Set("clientID", claims.ClientID)
+	}
+	if claims.Scope != "" || claims.ClientID != "" {
+		c.Set("scopes", claims.Scopes())
+	}
+	c.Set("tokenID", claims.ID)
+	c.Set("authMethod", scheme)
+}
 
-		// Set user ID to context
-		c.Set("userID", userID)
+func authenticateAPIKey(c *gin.Context, apiKeyService *services.APIKeyService, secret string) {
+	key, user, err := apiKeyService.AuthenticateKey(c.Request.Context(), secret)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, errors.ErrUnauthorized)
+		c.Abort()
+		return
+	}
+
+	// Set identity to context
+	c.Set("userID", user.ID.Hex())
+	c.Set("roles", user.Roles)
+	c.Set("scopes", key.Scopes)
+	c.Set("apiKeyID", key.ID.Hex())
+	c.Set("authMethod", "api_key")
+}
+
+// RequireUserSession rejects callers that did not authenticate with an
+// interactive user token, such as API keys and service clients. It must run
+// after AuthMiddleware.
+func RequireUserSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, isUser := c.Get("userID")
+		_, scoped := c.Get("scopes")
+		if !isUser || scoped {
+			c.JSON(http.StatusForbidden, errors.ErrForbidden)
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -1,25 +1,305 @@
 package middleware
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/cache"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/metrics"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
 )
 
-func CacheMiddleware(redis *redis.Client) gin.HandlerFunc {
+// cachedHeaders are the response headers replayed on a cache hit
+var cachedHeaders = []string{"Content-Type", "ETag"}
+
+// TagFunc derives an invalidation tag from a request. An empty tag is ignored.
+type TagFunc func(c *gin.Context) string
+
+// StaticTag tags every response of a route with the same tag
+func StaticTag(tag string) TagFunc {
+	return func(*gin.Context) string { return tag }
+}
+
+// UserParamTag tags a response with the user named by a path parameter
+func UserParamTag(param string) TagFunc {
+	return func(c *gin.Context) string { return cache.UserTag(c.Param(param)) }
+}
+
+// CallerTag tags a response with the authenticated user
+func CallerTag() TagFunc {
+	return func(c *gin.Context) string {
+		if userID := c.GetString("userID"); userID != "" {
+			return cache.UserTag(userID)
+		}
+		return ""
+	}
+}
+
+// ResponseCache caches successful GET responses per caller in Redis. Entries
+// live for the TTL configured for their route and are dropped early when a
+// write invalidates one of their tags.
+type ResponseCache struct {
+	store       *cache.Store
+	redisGuard  *redisguard.Guard
+	defaultTTL  time.Duration
+	routeTTLs   map[string]time.Duration
+	lockTimeout time.Duration
+}
+
+func NewResponseCache(store *cache.Store, redisGuard *redisguard.Guard, cfg *config.Config) *ResponseCache {
+	defaultTTL := time.Duration(cfg.Cache.DefaultTTL) * time.Second
+	if defaultTTL <= 0 {
+		defaultTTL = time.Minute
+	}
+
+	lockTimeout := time.Duration(cfg.Cache.LockTimeout) * time.Second
+	if lockTimeout <= 0 {
+		lockTimeout = 5 * time.Second
+	}
+
+	routeTTLs := make(map[string]time.Duration, len(cfg.Cache.RouteTTLs))
+	for route, seconds := range cfg.Cache.RouteTTLs {
+		routeTTLs[route] = time.Duration(seconds) * time.Second
+	}
+
+	return &ResponseCache{
+		store:       store,
+		redisGuard:  redisGuard,
+		defaultTTL:  defaultTTL,
+		routeTTLs:   routeTTLs,
+		lockTimeout: lockTimeout,
+	}
+}
+
+// CacheMiddleware caches the route's responses, tagging them with the given
+// tags. It must run after AuthMiddleware so that responses are keyed by
+// caller.
+func (rc *ResponseCache) CacheMiddleware(tags ...TagFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Check cache
-		key := c.Request.URL.Path
-		val, err := redis.Get(c.Request.Context(), key).Result()
-		if err == nil {
-			c.Header("X-Cache", "HIT")
-			c.Data(http.StatusOK, "application/json", []byte(val))
-			c.Abort()
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
 			return
 		}
 
-		// Continue if not found in cache
-		c.Next()
+		ttl := rc.ttl(c.FullPath())
+		if ttl <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key := cacheKey(c)
+		requestCacheControl := strings.ToLower(c.GetHeader("Cache-Control"))
+		noStore := strings.Contains(requestCacheControl, "no-store")
+
+		// Clients may ask for a fresh response; the result still refills
+		// the cache unless they also forbid storing it
+		if strings.Contains(requestCacheControl, "no-cache") || noStore {
+			metrics.CacheRequests.WithLabelValues(metrics.CacheResponses, metrics.CacheBypass).Inc()
+			c.Header("X-Cache", "BYPASS")
+			if noStore {
+				c.Next()
+				return
+			}
+			rc.fill(c, key, ttl, tags)
+			return
+		}
+
+		if entry, err := rc.store.Get(ctx, key); err == nil {
+			metrics.CacheRequests.WithLabelValues(metrics.CacheResponses, metrics.CacheHit).Inc()
+			serveEntry(c, entry)
+			return
+		} else if err != cache.ErrMiss {
+			switch err := rc.redisGuard.Degrade(redisguard.FeatureResponseCache, err); err {
+			case nil:
+			case redisguard.ErrUnavailable:
+				c.JSON(errors.GetHTTPStatusCode(err), err)
+				c.Abort()
+				return
+			default:
+				logger.WithContext(c.Request.Context()).Error("Response cache read failed", zap.Error(err))
+			}
+			metrics.CacheRequests.WithLabelValues(metrics.CacheResponses, metrics.CacheBypass).Inc()
+			c.Header("X-Cache", "BYPASS")
+			c.Next()
+			return
+		}
+
+		metrics.CacheRequests.WithLabelValues(metrics.CacheResponses, metrics.CacheMiss).Inc()
+
+		// Only one request fills a missing entry; the others wait for it
+		locked, err := rc.store.Lock(ctx, key, rc.lockTimeout)
+		if err == nil && !locked {
+			if entry := rc.waitForEntry(c, key); entry != nil {
+				serveEntry(c, entry)
+				return
+			}
+		}
+		if locked {
+			defer rc.store.Unlock(ctx, key)
+		}
+
+		c.Header("X-Cache", "MISS")
+		rc.fill(c, key, ttl, tags)
 	}
 }
+
+func (rc *ResponseCache) ttl(route string) time.Duration {
+	if ttl, ok := rc.routeTTLs[route]; ok {
+		return ttl
+	}
+	return rc.defaultTTL
+}
+
+// fill runs the handler and stores a successful response
+func (rc *ResponseCache) fill(c *gin.Context, key string, ttl time.Duration, tags []TagFunc) {
+	writer := &capturingWriter{
+		ResponseWriter: c.Writer,
+		cacheControl:   fmt.Sprintf("private, max-age=%d", int(ttl.Seconds())),
+	}
+	c.Writer = writer
+
+	c.Next()
+
+	// Handlers can opt a response out of caching with Cache-Control: no-store
+	if writer.Status() != http.StatusOK || strings.Contains(writer.Header().Get("Cache-Control"), "no-store") {
+		return
+	}
+
+	entry := &cache.Entry{
+		Status:   writer.Status(),
+		Header:   http.Header{},
+		Body:     writer.body.Bytes(),
+		StoredAt: time.Now(),
+	}
+	for _, name := range cachedHeaders {
+		if value := writer.Header().Get(name); value != "" {
+			entry.Header.Set(name, value)
+		}
+	}
+
+	var tagValues []string
+	for _, tag := range tags {
+		if value := tag(c); value != "" {
+			tagValues = append(tagValues, value)
+		}
+	}
+
+	if err := rc.store.Set(c.Request.Context(), key, entry, ttl, tagValues...); err != nil && err != redisguard.ErrUnavailable {
+		logger.WithContext(c.Request.Context()).Error("Response cache write failed", zap.Error(err))
+	}
+}
+
+// waitForEntry polls for an entry another request is filling, giving up when
+// the lock would have expired
+func (rc *ResponseCache) waitForEntry(c *gin.Context, key string) *cache.Entry {
+	deadline := time.Now().Add(rc.lockTimeout)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-c.Request.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if entry, err := rc.store.Get(c.Request.Context(), key); err == nil {
+			return entry
+		}
+	}
+	return nil
+}
+
+// serveEntry replays a cached response, answering conditional requests with
+// 304 when the ETag still matches
+func serveEntry(c *gin.Context, entry *cache.Entry) {
+	for name, values := range entry.Header {
+		for _, value := range values {
+			c.Writer.Header().Add(name, value)
+		}
+	}
+
+	remaining := int(time.Until(entry.ExpiresAt()).Seconds())
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.Header("X-Cache", "HIT")
+	c.Header("Age", strconv.Itoa(int(time.Since(entry.StoredAt).Seconds())))
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", remaining))
+
+	if etag := entry.Header.Get("ETag"); etag != "" && c.GetHeader("If-None-Match") == etag {
+		c.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+
+	c.Status(entry.Status)
+	if c.Request.Method != http.MethodHead {
+		_, _ = c.Writer.Write(entry.Body)
+	}
+	c.Abort()
+}
+
+// cacheKey identifies a response by method, route path, normalized query and
+// caller, so that one caller's response is never served to another
+func cacheKey(c *gin.Context) string {
+	query := c.Request.URL.Query()
+	for _, values := range query {
+		sort.Strings(values)
+	}
+
+	identity := "anonymous"
+	if userID := c.GetString("userID"); userID != "" {
+		identity = "user:" + userID
+	} else if clientID := c.GetString("clientID"); clientID != "" {
+		identity = "client:" + clientID
+	}
+
+	raw := strings.Join([]string{
+		c.Request.Method,
+		c.Request.URL.Path,
+		url.Values(query).Encode(),
+		identity,
+	}, "\n")
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// capturingWriter records the response body while writing it through, and
+// marks successful responses cacheable by the client for the entry's TTL
+type capturingWriter struct {
+	gin.ResponseWriter
+	body         bytes.Buffer
+	cacheControl string
+}
+
+func (w *capturingWriter) WriteHeader(code int) {
+	if code == http.StatusOK && w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", w.cacheControl)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/cache"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
+)
+
+// cacheTest serves /users/:id through the response cache, counting how often
+// the handler runs. The caller is taken from the X-User header.
+type cacheTest struct {
+	router *gin.Engine
+	store  *cache.Store
+	server *miniredis.Miniredis
+	calls  int
+}
+
+func newCacheTest(t *testing.T) *cacheTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+
+	guard, err := redisguard.New(client, nil, time.Minute)
+	if err != nil {
+		t.Fatalf("redisguard.New: %v", err)
+	}
+	if err := guard.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	var cfg config.Config
+	cfg.Cache.DefaultTTL = 60
+	cfg.Cache.LockTimeout = 1
+
+	ct := &cacheTest{
+		router: gin.New(),
+		store:  cache.NewStore(client, time.Hour),
+		server: server,
+	}
+	responseCache := NewResponseCache(ct.store, guard, &cfg)
+
+	ct.router.GET("/users/:id", func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-User"))
+	}, responseCache.CacheMiddleware(UserParamTag("id")), func(c *gin.Context) {
+		ct.calls++
+		c.Header("ETag", `"`+strconv.Itoa(ct.calls)+`"`)
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "call": ct.calls})
+	})
+	return ct
+}
+
+func (ct *cacheTest) get(path, user string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-User", user)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	ct.router.ServeHTTP(w, req)
+	return w
+}
+
+func expectCache(t *testing.T, w *httptest.ResponseRecorder, status int, xCache, body string) {
+	t.Helper()
+	if w.Code != status {
+		t.Errorf("status = %d, want %d", w.Code, status)
+	}
+	if got := w.Header().Get("X-Cache"); got != xCache {
+		t.Errorf("X-Cache = %q, want %q", got, xCache)
+	}
+	if body != "" && w.Body.String() != body {
+		t.Errorf("body = %s, want %s", w.Body.String(), body)
+	}
+}
+
+func TestResponseCacheHitAndMiss(t *testing.T) {
+	ct := newCacheTest(t)
+
+	expectCache(t, ct.get("/users/1", "1"), http.StatusOK, "MISS", `{"call":1,"id":"1"}`)
+
+	hit := ct.get("/users/1", "1")
+	expectCache(t, hit, http.StatusOK, "HIT", `{"call":1,"id":"1"}`)
+	if got := hit.Header().Get("ETag"); got != `"1"` {
+		t.Errorf("ETag = %q, want %q", got, `"1"`)
+	}
+
+	// The query is part of the key, in any order
+	expectCache(t, ct.get("/users/1?b=2&a=1", "1"), http.StatusOK, "MISS", "")
+	expectCache(t, ct.get("/users/1?a=1&b=2", "1"), http.StatusOK, "HIT", "")
+
+	// One caller's response is never served to another
+	expectCache(t, ct.get("/users/1", "2"), http.StatusOK, "MISS", "")
+
+	expectCache(t, ct.get("/users/1", "1", "If-None-Match", `"1"`), http.StatusNotModified, "HIT", "")
+
+	if ct.calls != 3 {
+		t.Errorf("handler ran %d times, want 3", ct.calls)
+	}
+
+	ct.server.FastForward(time.Minute)
+	expectCache(t, ct.get("/users/1", "1"), http.StatusOK, "MISS", "")
+}
+
+func TestResponseCacheInvalidate(t *testing.T) {
+	ct := newCacheTest(t)
+
+	ct.get("/users/1", "1")
+	ct.get("/users/1", "2")
+	ct.get("/users/2", "1")
+
+	if err := ct.store.Invalidate(context.Background(), cache.UserTag("1")); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+
+	// Every caller's copy of the user is dropped, other users stay cached
+	expectCache(t, ct.get("/users/1", "1"), http.StatusOK, "MISS", `{"call":4,"id":"1"}`)
+	expectCache(t, ct.get("/users/1", "2"), http.StatusOK, "MISS", "")
+	expectCache(t, ct.get("/users/2", "1"), http.StatusOK, "HIT", "")
+}
+
+func TestResponseCacheControl(t *testing.T) {
+	ct := newCacheTest(t)
+
+	ct.get("/users/1", "1")
+
+	// no-cache refreshes the entry, no-store leaves it alone
+	expectCache(t, ct.get("/users/1", "1", "Cache-Control", "no-cache"), http.StatusOK, "BYPASS", `{"call":2,"id":"1"}`)
+	expectCache(t, ct.get("/users/1", "1"), http.StatusOK, "HIT", `{"call":2,"id":"1"}`)
+	expectCache(t, ct.get("/users/1", "1", "Cache-Control", "no-store"), http.StatusOK, "BYPASS", `{"call":3,"id":"1"}`)
+	expectCache(t, ct.get("/users/1", "1"), http.StatusOK, "HIT", `{"call":2,"id":"1"}`)
+}
+
+func TestResponseCacheRedisDown(t *testing.T) {
+	ct := newCacheTest(t)
+
+	ct.get("/users/1", "1")
+	ct.server.Close()
+
+	// The response cache fails open, so requests reach the handler
+	expectCache(t, ct.get("/users/1", "1"), http.StatusOK, "BYPASS", `{"call":2,"id":"1"}`)
+	expectCache(t, ct.get("/users/1", "1"), http.StatusOK, "BYPASS", `{"call":3,"id":"1"}`)
+}
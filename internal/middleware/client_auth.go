@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/hashing"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
+)
+
+// ClientAuthMiddleware authenticates a service client using HTTP Basic
+// credentials, falling back to client_id and client_secret form fields as
+// allowed by RFC 6749 section 2.3.1
+func ClientAuthMiddleware(clientService *services.ClientService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, secret, ok := c.Request.BasicAuth()
+		if !ok {
+			clientID = c.PostForm("client_id")
+			secret = c.PostForm("client_secret")
+		}
+
+		client, err := clientService.AuthenticateClient(c.Request.Context(), clientID, secret)
+		if err == hashing.ErrBusy {
+			c.JSON(http.StatusServiceUnavailable, models.OAuthError{
+				Error:            "temporarily_unavailable",
+				ErrorDescription: err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.Header("WWW-Authenticate", `Basic realm="greeneye"`)
+			c.JSON(http.StatusUnauthorized, models.OAuthError{
+				Error:            "invalid_client",
+				ErrorDescription: "Client authentication failed",
+			})
+			c.Abort()
+			return
+		}
+
+		// Set client identity to context
+		c.Set("clientID", client.ClientID)
+		c.Set("client", client)
+		c.Request = c.Request.WithContext(logger.AddFields(c.Request.Context(), zap.String("client_id", client.ClientID)))
+
+		c.Next()
+	}
+}
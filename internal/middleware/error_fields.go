@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/tracing"
+)
+
+// ErrorField names a value to add to JSON error responses. An empty value is
+// left out.
+type ErrorField func(c *gin.Context) (key, value string)
+
+// TraceIDField adds the ID of the request's trace
+func TraceIDField(c *gin.Context) (string, string) {
+	return "trace_id", tracing.TraceID(c.Request.Context())
+}
+
+// ErrorFieldsMiddleware adds the given fields to every JSON object written
+// with an error status, so that clients can quote them when reporting a
+// problem. Handlers keep writing errors as before.
+func ErrorFieldsMiddleware(fields ...ErrorField) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := &errorBodyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		if writer.body.Len() == 0 {
+			return
+		}
+		writer.ResponseWriter.Write(writer.annotate(c, fields))
+	}
+}
+
+// errorBodyWriter holds back bodies written with an error status until the
+// handler is done
+type errorBodyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *errorBodyWriter) Write(data []byte) (int, error) {
+	if w.Status() < http.StatusBadRequest {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+func (w *errorBodyWriter) WriteString(s string) (int, error) {
+	if w.Status() < http.StatusBadRequest {
+		return w.ResponseWriter.WriteString(s)
+	}
+	return w.body.WriteString(s)
+}
+
+// annotate returns the held back body with the fields added, or unchanged if
+// it is not a JSON object
+func (w *errorBodyWriter) annotate(c *gin.Context, fields []ErrorField) []byte {
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return w.body.Bytes()
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(w.body.Bytes(), &object); err != nil || object == nil {
+		return w.body.Bytes()
+	}
+	for _, field := range fields {
+		if key, value := field(c); value != "" {
+			object[key] = value
+		}
+	}
+
+	annotated, err := json.Marshal(object)
+	if err != nil {
+		return w.body.Bytes()
+	}
+	return annotated
+}
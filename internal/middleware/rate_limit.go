@@ -1,28 +1,49 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/metrics"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
 )
 
+// RateLimiter limits requests per client IP in fixed windows. Counts are kept
+// in Redis so that every instance shares them. While Redis is down they are
+// kept in process if the rate_limit policy fails open, so each instance then
+// allows the full limit.
 type RateLimiter struct {
 	redisClient *redis.Client
+	redisGuard  *redisguard.Guard
+	local       *localCounter
 	limit       int
 	window      time.Duration
 }
 
-func RateLimit(redisClient *redis.Client) gin.HandlerFunc {
+func RateLimit(redisGuard *redisguard.Guard, cfg *config.Config) gin.HandlerFunc {
 	// Define rate limit parameters
-	limit := 100              // max requests
-	window := 1 * time.Minute // per minute
+	limit := cfg.RateLimit.Requests
+	if limit <= 0 {
+		limit = 100
+	}
+	window := time.Duration(cfg.RateLimit.Window) * time.Second
+	if window <= 0 {
+		window = time.Minute
+	}
 
 	rl := &RateLimiter{
-		redisClient: redisClient,
+		redisClient: redisGuard.Client(),
+		redisGuard:  redisGuard,
+		local:       &localCounter{windows: map[string]*localWindow{}},
 		limit:       limit,
 		window:      window,
 	}
@@ -35,20 +56,22 @@ func (rl *RateLimiter) HandleRateLimit(c *gin.Context) {
 	key := "rate_limit:" + clientIP
 
 	// Increment the count for this IP
-	count, err := rl.redisClient.Incr(c.Request.Context(), key).Result()
+	count, err := rl.count(c.Request.Context(), key)
+	if err == redisguard.ErrUnavailable {
+		c.JSON(errors.GetHTTPStatusCode(err), err)
+		c.Abort()
+		return
+	}
 	if err != nil {
 		// If Redis fails, allow the request but log the error
+		logger.WithContext(c.Request.Context()).Error("Rate limit check failed", zap.Error(err))
 		c.Next()
 		return
 	}
 
-	if count == 1 {
-		// Set expiration
-		rl.redisClient.Expire(c.Request.Context(), key, rl.window)
-	}
-
 	if count > int64(rl.limit) {
 		// Exceeded the limit
+		metrics.RateLimitRejections.WithLabelValues("ip").Inc()
 		c.JSON(http.StatusTooManyRequests, errors.New(
 			http.StatusTooManyRequests,
 			"Too Many Requests",
@@ -60,3 +83,58 @@ func (rl *RateLimiter) HandleRateLimit(c *gin.Context) {
 
 	c.Next()
 }
+
+// count returns the number of requests made under key in the current window
+func (rl *RateLimiter) count(ctx context.Context, key string) (int64, error) {
+	count, err := rl.redisClient.Incr(ctx, key).Result()
+	if err == nil {
+		if count == 1 {
+			// Set expiration
+			rl.redisClient.Expire(ctx, key, rl.window)
+		}
+		return count, nil
+	}
+
+	if err := rl.redisGuard.Degrade(redisguard.FeatureRateLimit, err); err != nil {
+		return 0, err
+	}
+	return rl.local.incr(key, rl.window), nil
+}
+
+// localCounter is the in-process fallback for counts kept in Redis
+type localCounter struct {
+	mu      sync.Mutex
+	windows map[string]*localWindow
+	swept   time.Time
+}
+
+type localWindow struct {
+	count   int64
+	expires time.Time
+}
+
+func (l *localCounter) incr(key string, window time.Duration) int64 {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Drop finished windows once per window so that the map stays bounded
+	// by the number of recent clients
+	if now.Sub(l.swept) >= window {
+		for k, w := range l.windows {
+			if now.After(w.expires) {
+				delete(l.windows, k)
+			}
+		}
+		l.swept = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.After(w.expires) {
+		w = &localWindow{expires: now.Add(window)}
+		l.windows[key] = w
+	}
+	w.count++
+	return w.count
+}
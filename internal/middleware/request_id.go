@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+)
+
+// RequestIDHeader carries the ID of a request between services
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern limits propagated IDs to what is safe to log and echo
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestIDMiddleware propagates the caller's X-Request-ID, or generates one,
+// and echoes it on the response
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = utils.GenerateRandomToken(16)
+		}
+
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// RequestIDField adds the request ID to error responses
+func RequestIDField(c *gin.Context) (string, string) {
+	return "request_id", c.GetString("requestID")
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+)
+
+// RequireScopes rejects scoped tokens that were not granted every listed
+// scope. It must run after AuthMiddleware. Interactive user sessions carry no
+// scopes and are governed by roles instead, so they pass through.
+func RequireScopes(required ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, scoped := c.Get("scopes")
+		if !scoped {
+			if _, isUser := c.Get("userID"); isUser {
+				c.Next()
+				return
+			}
+			c.JSON(http.StatusForbidden, errors.ErrForbidden)
+			c.Abort()
+			return
+		}
+
+		granted, _ := value.([]string)
+		for _, scope := range required {
+			if !slices.Contains(granted, scope) {
+				c.JSON(http.StatusForbidden, errors.New(
+					http.StatusForbidden,
+					"Forbidden",
+					"Missing required scope: "+scope,
+				))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRoles rejects callers whose token carries none of the listed roles.
+// It must run after AuthMiddleware.
+func RequireRoles(allowed ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("roles")
+		roles, _ := value.([]string)
+
+		for _, role := range roles {
+			if slices.Contains(allowed, role) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, errors.ErrForbidden)
+		c.Abort()
+	}
+}
+
+// RequireServiceClientOrRoles admits service clients acting on their own
+// behalf, whose scopes RequireScopes checks, and interactive user sessions
+// carrying one of the listed roles. It must run after AuthMiddleware.
+func RequireServiceClientOrRoles(allowed ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsServiceClient(c) || HasSessionRole(c, allowed...) {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, errors.ErrForbidden)
+		c.Abort()
+	}
+}
+
+// IsServiceClient reports whether the caller is a service client acting on
+// its own behalf rather than for a user
+func IsServiceClient(c *gin.Context) bool {
+	_, isUser := c.Get("userID")
+	return !isUser && c.GetString("clientID") != ""
+}
+
+// HasSessionRole reports whether the caller signed in interactively and
+// carries one of the listed roles. Roles of API keys do not count.
+func HasSessionRole(c *gin.Context, allowed ...string) bool {
+	_, isUser := c.Get("userID")
+	_, scoped := c.Get("scopes")
+	if !isUser || scoped {
+		return false
+	}
+
+	value, _ := c.Get("roles")
+	roles, _ := value.([]string)
+	for _, role := range roles {
+		if slices.Contains(allowed, role) {
+			return true
+		}
+	}
+	return false
+}
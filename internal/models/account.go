@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type AccountDeletionRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type AccountRestoreRequest struct {
+	MobileNumber string `json:"mobile_number" validate:"required_without=Email,omitempty,max=32"`
+	CountryCode  string `json:"country_code" validate:"omitempty,max=8"`
+	Email        string `json:"email" validate:"required_without=MobileNumber,omitempty,email,max=254"`
+	Password     string `json:"password" validate:"required"`
+}
+
+// AccountDeletionScheduled tells the user when their account will be purged
+type AccountDeletionScheduled struct {
+	PurgeAt time.Time `json:"purge_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit actions
+const (
+	AuditUserLogin                = "user.login"
+	AuditAccountDeletionRequested = "account.deletion_requested"
+	AuditAccountDeletionCancelled = "account.deletion_cancelled"
+	AuditAccountPurged            = "account.purged"
+	AuditDataExportRequested      = "data_export.requested"
+	AuditStatusChanged            = "user.status_changed"
+	AuditEmailVerified            = "user.email_verified"
+	AuditEmailRemoved             = "user.email_removed"
+	AuditHandleChanged            = "user.handle_changed"
+)
+
+// AuditEvent records a security-relevant change to a user account. ActorID is
+// the user or service that made the change, or "system" for background jobs.
+type AuditEvent struct {
+	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Action    string                 `bson:"action" json:"action"`
+	UserID    primitive.ObjectID     `bson:"user_id" json:"user_id"`
+	ActorID   string                 `bson:"actor_id" json:"actor_id"`
+	Details   map[string]interface{} `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt time.Time              `bson:"created_at" json:"created_at"`
+}
@@ -1,7 +1,11 @@
 package models
 
+// PasswordRecoveryRequest sends a reset link by SMS to the mobile number or
+// by email to the verified email address
 type PasswordRecoveryRequest struct {
-	MobileNumber string `json:"mobile_number" validate:"required,e164"`
+	MobileNumber string `json:"mobile_number" validate:"required_without=Email,omitempty,max=32"`
+	CountryCode  string `json:"country_code" validate:"omitempty,max=8"`
+	Email        string `json:"email" validate:"required_without=MobileNumber,omitempty,email,max=254"`
 }
 
 type ResetPasswordRequest struct {
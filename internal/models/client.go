@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ServiceClient is a machine identity allowed to call the API on its own behalf
+type ServiceClient struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ClientID   string             `bson:"client_id" json:"client_id"`
+	Name       string             `bson:"name" json:"name"`
+	SecretHash string             `bson:"secret_hash" json:"-"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	Disabled   bool               `bson:"disabled" json:"disabled"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Scopes that can be granted to service clients
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+)
+
+// ClientScopes lists every scope a service client may be granted
+var ClientScopes = []string{ScopeUsersRead, ScopeUsersWrite}
+
+type ClientRegistration struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=users:read users:write"`
+}
+
+// ClientCredentials is returned once when a client is registered. The secret
+// is never stored or shown again.
+type ClientCredentials struct {
+	Client       *ServiceClient `json:"client"`
+	ClientSecret string         `json:"client_secret"`
+}
+
+// ClientTokenRequest is the form body of a client credentials grant
+type ClientTokenRequest struct {
+	GrantType string `form:"grant_type" json:"grant_type" validate:"required"`
+	Scope     string `form:"scope" json:"scope"`
+}
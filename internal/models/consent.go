@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Policy document types users must agree to
+const (
+	PolicyTerms   = "terms_of_service"
+	PolicyPrivacy = "privacy_policy"
+)
+
+// PolicyTypes lists every policy type that requires consent
+var PolicyTypes = []string{PolicyTerms, PolicyPrivacy}
+
+// PolicyDocument is a published version of a terms or privacy document
+type PolicyDocument struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type        string             `bson:"type" json:"type"`
+	Version     string             `bson:"version" json:"version"`
+	URL         string             `bson:"url" json:"url"`
+	PublishedAt time.Time          `bson:"published_at" json:"published_at"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type PolicyPublishRequest struct {
+	Type        string     `json:"type" validate:"required,oneof=terms_of_service privacy_policy"`
+	Version     string     `json:"version" validate:"required,max=32"`
+	URL         string     `json:"url" validate:"required,url"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+// Consent records a user's acceptance of one policy version. Once the user
+// is purged only the policy version and time are kept.
+type Consent struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	PolicyType   string             `bson:"policy_type" json:"policy_type"`
+	Version      string             `bson:"version" json:"version"`
+	IPAddress    string             `bson:"ip_address" json:"ip_address"`
+	UserAgent    string             `bson:"user_agent" json:"user_agent"`
+	AcceptedAt   time.Time          `bson:"accepted_at" json:"accepted_at"`
+	AnonymizedAt *time.Time         `bson:"anonymized_at,omitempty" json:"anonymized_at,omitempty"`
+}
+
+type ConsentRequest struct {
+	PolicyType string `json:"policy_type" validate:"required,oneof=terms_of_service privacy_policy"`
+	Version    string `json:"version" validate:"required"`
+}
+
+// ConsentCoverage reports how many users accepted the current version of a policy
+type ConsentCoverage struct {
+	PolicyType    string  `json:"policy_type"`
+	Version       string  `json:"version"`
+	AcceptedUsers int64   `json:"accepted_users"`
+	TotalUsers    int64   `json:"total_users"`
+	Coverage      float64 `json:"coverage"`
+}
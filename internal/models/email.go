@@ -0,0 +1,9 @@
+package models
+
+type EmailChangeRequest struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+}
+
+type EmailVerificationRequest struct {
+	Token string `json:"token" validate:"required"`
+}
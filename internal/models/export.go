@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Data export job states
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+	ExportExpired   = "expired"
+)
+
+// DataExport is an asynchronous job that packages a user's personal data
+type DataExport struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	RequestedBy string             `bson:"requested_by" json:"requested_by"`
+	Status      string             `bson:"status" json:"status"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	FilePath    string             `bson:"file_path,omitempty" json:"-"`
+	SHA256      string             `bson:"sha256,omitempty" json:"sha256,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	CompletedAt *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+
+	// DownloadURL is a short-lived signed link, generated on each status read
+	DownloadURL string `bson:"-" json:"download_url,omitempty"`
+}
+
+// ExportManifest lists every file in an export archive with its SHA-256
+// digest. The archive's manifest.sig holds an HMAC-SHA256 of this manifest.
+type ExportManifest struct {
+	ExportID    string            `json:"export_id"`
+	UserID      string            `json:"user_id"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Files       map[string]string `json:"files"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reasons a handle cannot be claimed
+const (
+	HandleInvalid    = "invalid"
+	HandleReserved   = "reserved"
+	HandleNotAllowed = "not_allowed"
+	HandleTaken      = "taken"
+)
+
+type HandleChangeRequest struct {
+	Handle string `json:"handle" validate:"required,max=30"`
+}
+
+// HandleAvailability tells whether a handle can be claimed, and if not why
+type HandleAvailability struct {
+	Handle    string `json:"handle"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// HandleRedirect keeps a released handle pointing at its previous owner
+// until it expires, and stops other users from claiming it in the meantime
+type HandleRedirect struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Handle     string             `bson:"handle" json:"handle"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ReleasedAt time.Time          `bson:"released_at" json:"released_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// PublicProfile is the part of a user other services may show publicly
+type PublicProfile struct {
+	ID          primitive.ObjectID `json:"id"`
+	Handle      string             `json:"handle"`
+	DisplayName string             `json:"display_name,omitempty"`
+	AvatarURL   string             `json:"avatar_url,omitempty"`
+	Bio         string             `json:"bio,omitempty"`
+}
+
+// PublicProfile returns the fields of the user anyone may see
+func (u *User) PublicProfile() *PublicProfile {
+	return &PublicProfile{
+		ID:          u.ID,
+		Handle:      u.Handle,
+		DisplayName: u.DisplayName,
+		AvatarURL:   u.AvatarURL,
+		Bio:         u.Bio,
+	}
+}
@@ -0,0 +1,8 @@
+package models
+
+// LogLevelRequest changes the global log level, or a module's level when
+// module is set. An empty level removes the module's override.
+type LogLevelRequest struct {
+	Module string `json:"module" validate:"omitempty,max=64"`
+	Level  string `json:"level" validate:"required_without=Module,omitempty,oneof=debug info warn error"`
+}
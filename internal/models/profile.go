@@ -0,0 +1,14 @@
+package models
+
+// ProfileFieldRules maps each user-editable profile field to its validation tag
+var ProfileFieldRules = map[string]string{
+	"display_name": "max=64",
+	"locale":       "bcp47_language_tag",
+	"timezone":     "timezone",
+	"avatar_url":   "url,startswith=https://,max=2048",
+	"bio":          "max=500",
+}
+
+// ProfileUpdate is a validated set of profile changes keyed by field name.
+// A nil value clears the field.
+type ProfileUpdate map[string]*string
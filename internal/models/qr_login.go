@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// QR login session states
+const (
+	QRLoginPending  = "pending"
+	QRLoginApproved = "approved"
+	QRLoginDenied   = "denied"
+)
+
+// QRLoginSession is a pending cross-device login kept in Redis. The web client
+// that created it proves ownership with a secret whose hash is stored here,
+// and UserID is set to the user who approved or denied it.
+type QRLoginSession struct {
+	ID         string    `json:"id"`
+	Status     string    `json:"status"`
+	SecretHash string    `json:"secret_hash"`
+	UserID     string    `json:"user_id,omitempty"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// QRLoginCreated is returned to the web client that started the login
+type QRLoginCreated struct {
+	SessionID string `json:"session_id"`
+	Secret    string `json:"secret"`
+	QRPayload string `json:"qr_payload"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// QRLoginDetails is shown on the approving device so the user can check
+// where the login request came from
+type QRLoginDetails struct {
+	SessionID string    `json:"session_id"`
+	Status    string    `json:"status"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type QRLoginPollRequest struct {
+	Secret string `json:"secret" validate:"required"`
+}
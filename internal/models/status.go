@@ -0,0 +1,68 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User lifecycle states
+const (
+	StatusPending   = "pending"
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusBanned    = "banned"
+	StatusDeleted   = "deleted"
+)
+
+// statusTransitions lists the states each state may move to. Deleted
+// accounts can only return to their previous state through account restore.
+var statusTransitions = map[string][]string{
+	StatusPending:   {StatusActive, StatusSuspended, StatusBanned, StatusDeleted},
+	StatusActive:    {StatusSuspended, StatusBanned, StatusDeleted},
+	StatusSuspended: {StatusActive, StatusBanned, StatusDeleted},
+	StatusBanned:    {StatusActive, StatusDeleted},
+	StatusDeleted:   {StatusPending, StatusActive},
+}
+
+// CanTransition reports whether a user may move from one status to another
+func CanTransition(from, to string) bool {
+	return slices.Contains(statusTransitions[from], to)
+}
+
+// StatusAllowsLogin reports whether users in the given status may sign in
+func StatusAllowsLogin(status string) bool {
+	return status == StatusPending || status == StatusActive
+}
+
+// CurrentStatus returns the user's lifecycle status. Documents written before
+// statuses existed are active, or deleted when a purge is scheduled.
+func (u *User) CurrentStatus() string {
+	if u.Status != "" {
+		return u.Status
+	}
+	if u.PurgeAt != nil {
+		return StatusDeleted
+	}
+	return StatusActive
+}
+
+// StatusChange records one status transition of a user. ActorID is the user
+// or staff member who made the change, or "system" for background jobs.
+type StatusChange struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	FromStatus string             `bson:"from_status" json:"from_status"`
+	ToStatus   string             `bson:"to_status" json:"to_status"`
+	Reason     string             `bson:"reason" json:"reason"`
+	ActorID    string             `bson:"actor_id" json:"actor_id"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// StatusChangeRequest is submitted by support staff to change a user's
+// status. Deletion goes through the account deletion flow instead.
+type StatusChangeRequest struct {
+	Status string `json:"status" validate:"required,oneof=active suspended banned"`
+	Reason string `json:"reason" validate:"required,max=500"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// TokenIntrospectionRequest is the form body of an RFC 7662 introspection call
+type TokenIntrospectionRequest struct {
+	Token         string `form:"token" json:"token" validate:"required"`
+	TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+}
+
+// TokenRevocationRequest is the form body of an RFC 7009 revocation call
+type TokenRevocationRequest struct {
+	Token         string `form:"token" json:"token" validate:"required"`
+	TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+}
+
+// TokenIntrospection is the RFC 7662 introspection response
+type TokenIntrospection struct {
+	Active    bool     `json:"active"`
+	Subject   string   `json:"sub,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	TokenID   string   `json:"jti,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+
+	Confirmation *TokenConfirmation `json:"cnf,omitempty"`
+}
+
+// TokenConfirmation carries the JWK thumbprint of a DPoP-bound token
+type TokenConfirmation struct {
+	JKT string `json:"jkt"`
+}
+
+// TokenResponse is the RFC 6749 access token response
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// OAuthError is the error body defined by RFC 6749 section 5.2
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// Session is an unexpired access token issued to a user
+type Session struct {
+	TokenID   string    `json:"token_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -4,28 +4,79 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/fieldcrypt"
 )
 
 type User struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	MobileNumber string             `bson:"mobile_number" json:"mobile_number" validate:"required,e164"`
-	CountryCode  string             `bson:"country_code" json:"country_code" validate:"required"`
-	PasswordHash string             `bson:"password_hash" json:"-"`
-	IsVerified   bool               `bson:"is_verified" json:"is_verified"`
-	Roles        []string           `bson:"roles" json:"roles"`
-	LastLoginAt  time.Time          `bson:"last_login_at" json:"last_login_at"`
-	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	ID                  primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	MobileNumber        string               `bson:"mobile_number,omitempty" json:"mobile_number" validate:"required,e164"`
+	MobileNumberEnc     *fieldcrypt.Envelope `bson:"mobile_number_enc,omitempty" json:"-"`
+	MobileNumberIndex   string               `bson:"mobile_number_index,omitempty" json:"-"`
+	CountryCode         string               `bson:"country_code" json:"country_code" validate:"required"`
+	Region              string               `bson:"region,omitempty" json:"region,omitempty"`
+	NumberType          string               `bson:"number_type,omitempty" json:"number_type,omitempty"`
+	Email               string               `bson:"-" json:"email,omitempty"`
+	EmailEnc            *fieldcrypt.Envelope `bson:"email_enc,omitempty" json:"-"`
+	EmailIndex          string               `bson:"email_index,omitempty" json:"-"`
+	EmailVerifiedAt     *time.Time           `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	PasswordHash        string               `bson:"password_hash" json:"-"`
+	IsVerified          bool                 `bson:"is_verified" json:"is_verified"`
+	Roles               []string             `bson:"roles" json:"roles"`
+	Status              string               `bson:"status,omitempty" json:"status,omitempty"`
+	StatusReason        string               `bson:"status_reason,omitempty" json:"status_reason,omitempty"`
+	StatusChangedAt     *time.Time           `bson:"status_changed_at,omitempty" json:"status_changed_at,omitempty"`
+	StatusChangedBy     string               `bson:"status_changed_by,omitempty" json:"-"`
+	Handle              string               `bson:"handle,omitempty" json:"handle,omitempty"`
+	HandleChangedAt     *time.Time           `bson:"handle_changed_at,omitempty" json:"handle_changed_at,omitempty"`
+	DisplayName         string               `bson:"display_name,omitempty" json:"display_name,omitempty"`
+	Locale              string               `bson:"locale,omitempty" json:"locale,omitempty"`
+	Timezone            string               `bson:"timezone,omitempty" json:"timezone,omitempty"`
+	AvatarURL           string               `bson:"avatar_url,omitempty" json:"avatar_url,omitempty"`
+	Bio                 string               `bson:"bio,omitempty" json:"bio,omitempty"`
+	Version             int64                `bson:"version" json:"version"`
+	DeletionRequestedAt *time.Time           `bson:"deletion_requested_at,omitempty" json:"deletion_requested_at,omitempty"`
+	PurgeAt             *time.Time           `bson:"purge_at,omitempty" json:"purge_at,omitempty"`
+	PurgeClaimedAt      *time.Time           `bson:"purge_claimed_at,omitempty" json:"-"`
+	LastLoginAt         time.Time            `bson:"last_login_at" json:"last_login_at"`
+	CreatedAt           time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt           time.Time            `bson:"updated_at" json:"updated_at"`
 }
 
+// UserRegistration accepts the mobile number in international or national
+// format, with the country as an ISO region ("IN") or calling code ("+91")
 type UserRegistration struct {
-	MobileNumber string `json:"mobile_number" validate:"required,e164"`
-	CountryCode  string `json:"country_code" validate:"required"`
+	MobileNumber string `json:"mobile_number" validate:"required,max=32"`
+	CountryCode  string `json:"country_code" validate:"required,max=8"`
 	Password     string `json:"password" validate:"required,min=8,max=72"`
 	OTPCode      string `json:"otp_code" validate:"required"`
+
+	// Versions of the current policies the user accepted when signing up
+	TermsVersion   string `json:"terms_version"`
+	PrivacyVersion string `json:"privacy_version"`
+
+	// Set by the handler for consent records, never bound from the request body
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
+// UserLogin identifies the user by mobile number or verified email. A mobile
+// number in national format needs the country it belongs to.
 type UserLogin struct {
-	MobileNumber string `json:"mobile_number" validate:"required,e164"`
+	MobileNumber string `json:"mobile_number" validate:"required_without=Email,omitempty,max=32"`
+	CountryCode  string `json:"country_code" validate:"omitempty,max=8"`
+	Email        string `json:"email" validate:"required_without=MobileNumber,omitempty,email,max=254"`
 	Password     string `json:"password" validate:"required"`
-}
\ No newline at end of file
+
+	// Set by the handler for login history, never bound from the request body
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// LoginResult is returned by a successful login. ConsentRequired is set when
+// the user has not accepted the current version of every policy.
+type LoginResult struct {
+	Token           string
+	ConsentRequired bool
+	PendingPolicies []*PolicyDocument
+}
@@ -0,0 +1,56 @@
+// Package blocklist loads word lists from text files with one entry per
+// line. Blank lines and lines starting with # are ignored.
+package blocklist
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// List is a set of lower-cased entries
+type List struct {
+	entries map[string]struct{}
+}
+
+// Load reads a list from a file
+func Load(path string) (*List, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("blocklist: %w", err)
+	}
+	defer file.Close()
+
+	list := &List{entries: map[string]struct{}{}}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list.entries[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("blocklist: reading %s: %w", path, err)
+	}
+
+	return list, nil
+}
+
+// Contains reports whether value is on the list, ignoring case
+func (l *List) Contains(value string) bool {
+	_, ok := l.entries[strings.ToLower(value)]
+	return ok
+}
+
+// ContainedIn reports whether any entry occurs within value, ignoring case
+func (l *List) ContainedIn(value string) bool {
+	value = strings.ToLower(value)
+	for entry := range l.entries {
+		if strings.Contains(value, entry) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+// Package cache stores HTTP responses in Redis and invalidates them by tag.
+// It lives outside the middleware package so that services can invalidate
+// cached responses when they write without importing the HTTP layer.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	entryKeyFormat = "cache:resp:%s"
+	tagKeyFormat   = "cache:tag:%s"
+	lockKeyFormat  = "cache:lock:%s"
+)
+
+// UsersTag is attached to every response listing users
+const UsersTag = "users"
+
+// UserTag is attached to every response that includes the given user
+func UserTag(userID string) string {
+	return "user:" + userID
+}
+
+// ErrMiss is returned by Get when no entry is stored for a key
+var ErrMiss = errors.New("cache: miss")
+
+// Entry is a stored response
+type Entry struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	StoredAt time.Time   `json:"stored_at"`
+	TTL      int64       `json:"ttl"`
+}
+
+// ExpiresAt returns when the entry stops being served
+func (e *Entry) ExpiresAt() time.Time {
+	return e.StoredAt.Add(time.Duration(e.TTL) * time.Second)
+}
+
+// Store keeps cached entries and the tag sets used to invalidate them
+type Store struct {
+	redisClient *redis.Client
+	// tagTTL bounds how long a tag set lives. It must be at least the
+	// longest entry TTL so that every entry stays reachable from its tags.
+	tagTTL time.Duration
+}
+
+func NewStore(redisClient *redis.Client, tagTTL time.Duration) *Store {
+	return &Store{
+		redisClient: redisClient,
+		tagTTL:      tagTTL,
+	}
+}
+
+// Get returns the entry stored under key, or ErrMiss
+func (s *Store) Get(ctx context.Context, key string) (*Entry, error) {
+	data, err := s.redisClient.Get(ctx, fmt.Sprintf(entryKeyFormat, key)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Set stores an entry for ttl and adds it to each tag
+func (s *Store) Set(ctx context.Context, key string, entry *Entry, ttl time.Duration, tags ...string) error {
+	entry.TTL = int64(ttl.Seconds())
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	entryKey := fmt.Sprintf(entryKeyFormat, key)
+	pipe := s.redisClient.TxPipeline()
+	pipe.Set(ctx, entryKey, data, ttl)
+	for _, tag := range tags {
+		tagKey := fmt.Sprintf(tagKeyFormat, tag)
+		pipe.SAdd(ctx, tagKey, entryKey)
+		pipe.Expire(ctx, tagKey, s.tagTTL)
+	}
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Invalidate removes every entry carrying any of the tags
+func (s *Store) Invalidate(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		tagKey := fmt.Sprintf(tagKeyFormat, tag)
+		keys, err := s.redisClient.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return err
+		}
+
+		if err := s.redisClient.Del(ctx, append(keys, tagKey)...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lock claims the right to fill the entry for key, so that concurrent misses
+// wait for one request instead of all hitting the backend. The lock expires
+// after ttl in case its holder never releases it.
+func (s *Store) Lock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return s.redisClient.SetNX(ctx, fmt.Sprintf(lockKeyFormat, key), "1", ttl).Result()
+}
+
+// Unlock releases a lock taken with Lock
+func (s *Store) Unlock(ctx context.Context, key string) error {
+	return s.redisClient.Del(ctx, fmt.Sprintf(lockKeyFormat, key)).Err()
+}
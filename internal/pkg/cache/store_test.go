@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewStore(client, time.Hour), server
+}
+
+func testEntry(body string) *Entry {
+	return &Entry{
+		Status:   http.StatusOK,
+		Header:   http.Header{"Content-Type": {"application/json"}},
+		Body:     []byte(body),
+		StoredAt: time.Now(),
+	}
+}
+
+func TestStoreGetSet(t *testing.T) {
+	s, server := newTestStore(t)
+	ctx := context.Background()
+
+	if _, err := s.Get(ctx, "profile"); err != ErrMiss {
+		t.Fatalf("Get before Set error = %v, want %v", err, ErrMiss)
+	}
+
+	if err := s.Set(ctx, "profile", testEntry(`{"id":"1"}`), time.Minute, UserTag("1")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	entry, err := s.Get(ctx, "profile")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(entry.Body) != `{"id":"1"}` || entry.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Get = %d %v %s, want the stored entry", entry.Status, entry.Header, entry.Body)
+	}
+	if entry.TTL != 60 {
+		t.Errorf("TTL = %d, want 60", entry.TTL)
+	}
+
+	server.FastForward(time.Minute)
+	if _, err := s.Get(ctx, "profile"); err != ErrMiss {
+		t.Fatalf("Get after TTL error = %v, want %v", err, ErrMiss)
+	}
+}
+
+func TestStoreInvalidate(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+
+	entries := map[string][]string{
+		"profile:1": {UserTag("1")},
+		"profile:2": {UserTag("2")},
+		"list":      {UsersTag, UserTag("1"), UserTag("2")},
+	}
+	for key, tags := range entries {
+		if err := s.Set(ctx, key, testEntry(key), time.Minute, tags...); err != nil {
+			t.Fatalf("Set(%s): %v", key, err)
+		}
+	}
+
+	if err := s.Invalidate(ctx, UserTag("1")); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	for key, want := range map[string]error{"profile:1": ErrMiss, "list": ErrMiss, "profile:2": nil} {
+		if _, err := s.Get(ctx, key); err != want {
+			t.Errorf("Get(%s) error = %v, want %v", key, err, want)
+		}
+	}
+
+	// Invalidating a tag with no entries is not an error
+	if err := s.Invalidate(ctx, UserTag("1"), UserTag("3")); err != nil {
+		t.Fatalf("Invalidate of empty tags: %v", err)
+	}
+}
+
+func TestStoreLock(t *testing.T) {
+	s, server := newTestStore(t)
+	ctx := context.Background()
+
+	locked, err := s.Lock(ctx, "profile", time.Second)
+	if err != nil || !locked {
+		t.Fatalf("Lock = %v, %v, want true", locked, err)
+	}
+	if locked, _ := s.Lock(ctx, "profile", time.Second); locked {
+		t.Fatal("Lock succeeded while held")
+	}
+
+	if err := s.Unlock(ctx, "profile"); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if locked, _ := s.Lock(ctx, "profile", time.Second); !locked {
+		t.Fatal("Lock failed after Unlock")
+	}
+
+	// An abandoned lock expires
+	server.FastForward(time.Second)
+	if locked, _ := s.Lock(ctx, "profile", time.Second); !locked {
+		t.Fatal("Lock failed after the previous lock expired")
+	}
+}
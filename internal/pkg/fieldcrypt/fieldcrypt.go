@@ -0,0 +1,230 @@
+// Package fieldcrypt encrypts individual document fields with envelope
+// encryption and derives keyed blind indexes for looking them up.
+//
+// Every value is encrypted with its own random data key, which is in turn
+// wrapped by a key encryption key from the keyring. Rotating the keyring only
+// requires re-wrapping the data keys, not re-encrypting the values.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const keySize = 32
+
+var (
+	// ErrUnknownKey is returned when a value was wrapped with a key that is
+	// no longer in the keyring
+	ErrUnknownKey = errors.New("fieldcrypt: unknown key")
+	// ErrDecrypt is returned when a value cannot be authenticated
+	ErrDecrypt = errors.New("fieldcrypt: decryption failed")
+)
+
+// Envelope is an encrypted field value as stored in the database. The nonce
+// is prepended to both the wrapped key and the ciphertext.
+type Envelope struct {
+	KeyID      string `bson:"key_id" json:"key_id"`
+	WrappedKey []byte `bson:"wrapped_key" json:"wrapped_key"`
+	Ciphertext []byte `bson:"ciphertext" json:"ciphertext"`
+}
+
+// Keyring holds the key encryption keys and the blind index key
+type Keyring struct {
+	activeKeyID string
+	keys        map[string][]byte
+	indexKey    []byte
+}
+
+// keyringFile is the on-disk keyring format. Keys are base64 encoded.
+type keyringFile struct {
+	ActiveKey string            `json:"active_key"`
+	Keys      map[string]string `json:"keys"`
+	IndexKey  string            `json:"index_key"`
+}
+
+// LoadKeyring reads a JSON keyring file of the form
+//
+//	{"active_key": "k1", "keys": {"k1": "<base64>"}, "index_key": "<base64>"}
+//
+// where every key is 32 random bytes
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fieldcrypt: reading keyring: %w", err)
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("fieldcrypt: parsing keyring: %w", err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("fieldcrypt: key %q is not valid base64", id)
+		}
+		keys[id] = key
+	}
+
+	indexKey, err := base64.StdEncoding.DecodeString(file.IndexKey)
+	if err != nil {
+		return nil, errors.New("fieldcrypt: index key is not valid base64")
+	}
+
+	return NewKeyring(file.ActiveKey, keys, indexKey)
+}
+
+// NewKeyring builds a keyring that wraps new data keys with the active key
+func NewKeyring(activeKeyID string, keys map[string][]byte, indexKey []byte) (*Keyring, error) {
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("fieldcrypt: active key %q is not in the keyring", activeKeyID)
+	}
+	for id, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("fieldcrypt: key %q must be %d bytes", id, keySize)
+		}
+	}
+	if len(indexKey) != keySize {
+		return nil, fmt.Errorf("fieldcrypt: index key must be %d bytes", keySize)
+	}
+
+	return &Keyring{
+		activeKeyID: activeKeyID,
+		keys:        keys,
+		indexKey:    indexKey,
+	}, nil
+}
+
+// ActiveKeyID returns the ID of the key that wraps newly encrypted values
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeKeyID
+}
+
+// Encrypt seals a field value under a fresh data key. The field name is
+// authenticated so a value cannot be moved to another field.
+func (k *Keyring) Encrypt(field, plaintext string) (*Envelope, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(dataKey, []byte(plaintext), []byte(field))
+	if err != nil {
+		return nil, err
+	}
+
+	return k.wrap(dataKey, ciphertext)
+}
+
+// Decrypt opens a value sealed by Encrypt for the same field
+func (k *Keyring) Decrypt(field string, env *Envelope) (string, error) {
+	dataKey, err := k.unwrap(env)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dataKey, env.Ciphertext, []byte(field))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRewrap reports whether a value is wrapped by a key other than the
+// active one
+func (k *Keyring) NeedsRewrap(env *Envelope) bool {
+	return env.KeyID != k.activeKeyID
+}
+
+// Rewrap re-wraps the value's data key under the active key, leaving the
+// ciphertext unchanged
+func (k *Keyring) Rewrap(env *Envelope) (*Envelope, error) {
+	dataKey, err := k.unwrap(env)
+	if err != nil {
+		return nil, err
+	}
+	return k.wrap(dataKey, env.Ciphertext)
+}
+
+// BlindIndex derives a deterministic keyed hash of a field value that can be
+// stored and queried for equality without revealing the value
+func (k *Keyring) BlindIndex(field, value string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (k *Keyring) wrap(dataKey, ciphertext []byte) (*Envelope, error) {
+	wrapped, err := seal(k.keys[k.activeKeyID], dataKey, []byte(k.activeKeyID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		KeyID:      k.activeKeyID,
+		WrappedKey: wrapped,
+		Ciphertext: ciphertext,
+	}, nil
+}
+
+func (k *Keyring) unwrap(env *Envelope) ([]byte, error) {
+	kek, ok := k.keys[env.KeyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return open(kek, env.WrappedKey, []byte(env.KeyID))
+}
+
+// seal encrypts with AES-256-GCM and prepends the random nonce
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
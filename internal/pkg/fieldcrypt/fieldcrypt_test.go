@@ -0,0 +1,235 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func randomKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	return key
+}
+
+// testKeyring builds a keyring, standing in for one loaded after a rotation
+// when called again with the same keys and another active key
+func testKeyring(t *testing.T, keys map[string][]byte, indexKey []byte, active string) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(active, keys, indexKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return k
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	keys := map[string][]byte{"k1": randomKey(t)}
+	k := testKeyring(t, keys, randomKey(t), "k1")
+
+	for _, plaintext := range []string{"+919876543210", "user@example.com", ""} {
+		env, err := k.Encrypt("mobile_number", plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", plaintext, err)
+		}
+		if env.KeyID != "k1" {
+			t.Errorf("KeyID = %q, want k1", env.KeyID)
+		}
+		if plaintext != "" && bytes.Contains(env.Ciphertext, []byte(plaintext)) {
+			t.Errorf("ciphertext contains %q", plaintext)
+		}
+
+		got, err := k.Decrypt("mobile_number", env)
+		if err != nil {
+			t.Fatalf("Decrypt(%q): %v", plaintext, err)
+		}
+		if got != plaintext {
+			t.Errorf("Decrypt = %q, want %q", got, plaintext)
+		}
+	}
+
+	// Each value gets its own data key and nonce
+	a, _ := k.Encrypt("email", "user@example.com")
+	b, _ := k.Encrypt("email", "user@example.com")
+	if bytes.Equal(a.Ciphertext, b.Ciphertext) || bytes.Equal(a.WrappedKey, b.WrappedKey) {
+		t.Error("encrypting a value twice gave the same envelope")
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	keys := map[string][]byte{"k1": randomKey(t)}
+	k := testKeyring(t, keys, randomKey(t), "k1")
+
+	env, err := k.Encrypt("email", "user@example.com")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	flip := func(b []byte) []byte {
+		c := bytes.Clone(b)
+		c[len(c)-1] ^= 1
+		return c
+	}
+	cases := []struct {
+		name  string
+		field string
+		env   *Envelope
+		want  error
+	}{
+		{"other field", "mobile_number", env, ErrDecrypt},
+		{"modified ciphertext", "email", &Envelope{KeyID: "k1", WrappedKey: env.WrappedKey, Ciphertext: flip(env.Ciphertext)}, ErrDecrypt},
+		{"modified wrapped key", "email", &Envelope{KeyID: "k1", WrappedKey: flip(env.WrappedKey), Ciphertext: env.Ciphertext}, ErrDecrypt},
+		{"truncated ciphertext", "email", &Envelope{KeyID: "k1", WrappedKey: env.WrappedKey, Ciphertext: env.Ciphertext[:4]}, ErrDecrypt},
+		{"unknown key", "email", &Envelope{KeyID: "k9", WrappedKey: env.WrappedKey, Ciphertext: env.Ciphertext}, ErrUnknownKey},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := k.Decrypt(tc.field, tc.env); err != tc.want {
+				t.Errorf("Decrypt error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	keys := map[string][]byte{"k1": randomKey(t), "k2": randomKey(t)}
+	indexKey := randomKey(t)
+	before := testKeyring(t, keys, indexKey, "k1")
+	after := testKeyring(t, keys, indexKey, "k2")
+
+	env, err := before.Encrypt("mobile_number", "+919876543210")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if before.NeedsRewrap(env) {
+		t.Error("NeedsRewrap is true under the key that wrapped the value")
+	}
+	if !after.NeedsRewrap(env) {
+		t.Fatal("NeedsRewrap is false after rotating the active key")
+	}
+
+	rewrapped, err := after.Rewrap(env)
+	if err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	if rewrapped.KeyID != "k2" {
+		t.Errorf("KeyID = %q, want k2", rewrapped.KeyID)
+	}
+	if !bytes.Equal(rewrapped.Ciphertext, env.Ciphertext) {
+		t.Error("Rewrap changed the ciphertext")
+	}
+	if after.NeedsRewrap(rewrapped) {
+		t.Error("NeedsRewrap is still true after Rewrap")
+	}
+
+	got, err := after.Decrypt("mobile_number", rewrapped)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if got != "+919876543210" {
+		t.Errorf("Decrypt = %q, want +919876543210", got)
+	}
+
+	// Once the old key is retired, values still wrapped by it cannot be read
+	retired := testKeyring(t, map[string][]byte{"k2": keys["k2"]}, indexKey, "k2")
+	if _, err := retired.Decrypt("mobile_number", env); err != ErrUnknownKey {
+		t.Errorf("Decrypt with retired key error = %v, want %v", err, ErrUnknownKey)
+	}
+	if _, err := retired.Rewrap(env); err != ErrUnknownKey {
+		t.Errorf("Rewrap with retired key error = %v, want %v", err, ErrUnknownKey)
+	}
+	if _, err := retired.Decrypt("mobile_number", rewrapped); err != nil {
+		t.Errorf("Decrypt of rewrapped value with retired key: %v", err)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	keys := map[string][]byte{"k1": randomKey(t), "k2": randomKey(t)}
+	indexKey := randomKey(t)
+	k := testKeyring(t, keys, indexKey, "k1")
+
+	index := k.BlindIndex("email", "user@example.com")
+	if index != k.BlindIndex("email", "user@example.com") {
+		t.Error("BlindIndex is not deterministic")
+	}
+	if strings.Contains(index, "user") {
+		t.Errorf("BlindIndex %q reveals the value", index)
+	}
+	if index == k.BlindIndex("mobile_number", "user@example.com") {
+		t.Error("BlindIndex does not depend on the field")
+	}
+	if index == k.BlindIndex("email", "other@example.com") {
+		t.Error("BlindIndex does not depend on the value")
+	}
+
+	// Rotating the active key keeps indexes stable
+	if got := testKeyring(t, keys, indexKey, "k2").BlindIndex("email", "user@example.com"); got != index {
+		t.Errorf("BlindIndex changed with the active key: %s, want %s", got, index)
+	}
+	if got := testKeyring(t, keys, randomKey(t), "k1").BlindIndex("email", "user@example.com"); got == index {
+		t.Error("BlindIndex does not depend on the index key")
+	}
+}
+
+func TestNewKeyringValidation(t *testing.T) {
+	key := randomKey(t)
+	cases := []struct {
+		name     string
+		active   string
+		keys     map[string][]byte
+		indexKey []byte
+	}{
+		{"active key missing", "k2", map[string][]byte{"k1": key}, key},
+		{"short key", "k1", map[string][]byte{"k1": key, "k0": key[:16]}, key},
+		{"short index key", "k1", map[string][]byte{"k1": key}, key[:16]},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewKeyring(tc.active, tc.keys, tc.indexKey); err == nil {
+				t.Error("NewKeyring accepted an invalid keyring")
+			}
+		})
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	key, indexKey := randomKey(t), randomKey(t)
+	data, err := json.Marshal(map[string]interface{}{
+		"active_key": "k1",
+		"keys":       map[string]string{"k1": base64.StdEncoding.EncodeToString(key)},
+		"index_key":  base64.StdEncoding.EncodeToString(indexKey),
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	loaded, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+	if loaded.ActiveKeyID() != "k1" {
+		t.Errorf("ActiveKeyID = %q, want k1", loaded.ActiveKeyID())
+	}
+
+	// Values encrypted with the loaded keyring open with the same keys
+	env, err := loaded.Encrypt("email", "user@example.com")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	same := testKeyring(t, map[string][]byte{"k1": key}, indexKey, "k1")
+	if got, err := same.Decrypt("email", env); err != nil || got != "user@example.com" {
+		t.Errorf("Decrypt = %q, %v, want user@example.com", got, err)
+	}
+}
@@ -0,0 +1,99 @@
+// Package hashing runs bcrypt on a bounded number of goroutines, so that a
+// burst of logins cannot use up every core and starve the rest of the API.
+package hashing
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/metrics"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/tracing"
+)
+
+// ErrBusy is returned when the queue of waiting hashes is full
+var ErrBusy = errors.New(http.StatusServiceUnavailable, "Server is busy, try again later")
+
+// Pool limits how many bcrypt operations run at once. Callers beyond the
+// limit wait in a bounded queue; once the queue is full they are turned away
+// with ErrBusy instead of piling up.
+type Pool struct {
+	slots  chan struct{}
+	admits chan struct{}
+}
+
+// NewPool returns a pool running at most concurrency operations with up to
+// queueDepth more waiting for a slot
+func NewPool(concurrency, queueDepth int) *Pool {
+	return &Pool{
+		slots:  make(chan struct{}, concurrency),
+		admits: make(chan struct{}, concurrency+queueDepth),
+	}
+}
+
+// Hash hashes a password or secret
+func (p *Pool) Hash(ctx context.Context, password string) (string, error) {
+	ctx, span := tracing.Start(ctx, "hashing.Hash")
+	defer span.End()
+
+	var hash []byte
+	var err error
+	if runErr := p.run(ctx, func() {
+		hash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	}); runErr != nil {
+		return "", runErr
+	}
+	return string(hash), err
+}
+
+// Compare verifies a password against its hash. It returns
+// bcrypt.ErrMismatchedHashAndPassword when they do not match, and ErrBusy or
+// the context's error when the comparison never ran.
+func (p *Pool) Compare(ctx context.Context, password, hash string) error {
+	ctx, span := tracing.Start(ctx, "hashing.Compare")
+	defer span.End()
+
+	var err error
+	if runErr := p.run(ctx, func() {
+		err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	}); runErr != nil {
+		return runErr
+	}
+	return err
+}
+
+// run waits for a slot and calls fn in it. A caller whose context ends while
+// it waits gives up its place without running fn.
+func (p *Pool) run(ctx context.Context, fn func()) error {
+	span := trace.SpanFromContext(ctx)
+
+	select {
+	case p.admits <- struct{}{}:
+	default:
+		metrics.PasswordHashRejections.Inc()
+		tracing.RecordError(span, ErrBusy)
+		return ErrBusy
+	}
+	defer func() { <-p.admits }()
+
+	start := time.Now()
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		tracing.RecordError(span, ctx.Err())
+		return ctx.Err()
+	}
+	defer func() { <-p.slots }()
+
+	// Separates time spent queueing from time spent in bcrypt
+	wait := time.Since(start)
+	metrics.PasswordHashQueueWait.Observe(wait.Seconds())
+	span.AddEvent("slot acquired", trace.WithAttributes(attribute.Int64("queue_wait_ms", wait.Milliseconds())))
+	fn()
+	return nil
+}
@@ -0,0 +1,146 @@
+// Package health reports whether the service can take traffic. Liveness only
+// says the process is running; readiness also checks the dependencies every
+// request needs and turns false once shutdown has begun. Dependencies the
+// service can run without only mark it degraded.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+)
+
+// Overall and per-dependency states
+const (
+	StatusReady        = "ready"
+	StatusDegraded     = "degraded"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+
+	StateUp   = "up"
+	StateDown = "down"
+)
+
+// CheckFunc pings a dependency. It must return once ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+// Dependency is the outcome of one check
+type Dependency struct {
+	State     string  `json:"state"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of a readiness check
+type Report struct {
+	Status       string                `json:"status"`
+	Dependencies map[string]Dependency `json:"dependencies"`
+}
+
+// Ready reports whether the service should receive traffic
+func (r Report) Ready() bool {
+	return r.Status == StatusReady || r.Status == StatusDegraded
+}
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	optional bool
+}
+
+// Checker runs the readiness checks
+type Checker struct {
+	timeout      time.Duration
+	checks       []check
+	shuttingDown atomic.Bool
+}
+
+// NewChecker returns a checker that gives each dependency timeout to answer
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a dependency the service cannot run without. It is not safe
+// to call once checks have started.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// AddOptional registers a dependency whose outage leaves the service ready
+// but degraded
+func (c *Checker) AddOptional(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn, optional: true})
+}
+
+// ShutDown makes every later readiness check fail so that traffic is routed
+// away while in-flight requests finish
+func (c *Checker) ShutDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Check runs every dependency check concurrently
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{
+		Status:       StatusReady,
+		Dependencies: make(map[string]Dependency, len(c.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chk := range c.checks {
+		wg.Add(1)
+		go func(chk check) {
+			defer wg.Done()
+			dep := c.run(ctx, chk.name, chk.fn)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Dependencies[chk.name] = dep
+			switch {
+			case dep.State == StateUp:
+			case !chk.optional:
+				report.Status = StatusNotReady
+			case report.Status == StatusReady:
+				report.Status = StatusDegraded
+			}
+		}(chk)
+	}
+	wg.Wait()
+
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+// run checks one dependency. Errors are logged rather than reported, since
+// they can name internal hosts.
+func (c *Checker) run(ctx context.Context, name string, fn CheckFunc) Dependency {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx)
+	dep := Dependency{
+		State:     StateUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		logger.WithContext(ctx).Warn("Dependency check failed", zap.String("dependency", name), zap.Error(err))
+		dep.State = StateDown
+		dep.Error = "unavailable"
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+			dep.Error = "timeout"
+		}
+	}
+	return dep
+}
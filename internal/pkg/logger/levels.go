@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levels holds the global log level and per-module overrides. A module is
+// the package an entry is logged from, such as "services" or "handlers".
+// Both can be changed at runtime.
+var levels = &levelSet{
+	global:  zap.NewAtomicLevelAt(zapcore.InfoLevel),
+	modules: map[string]zapcore.Level{},
+}
+
+type levelSet struct {
+	global zap.AtomicLevel
+
+	mu      sync.RWMutex
+	modules map[string]zapcore.Level
+}
+
+// Levels reports the current log levels
+type Levels struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+// GetLevels returns the global level and the module overrides
+func GetLevels() Levels {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+
+	current := Levels{
+		Level:   levels.global.Level().String(),
+		Modules: make(map[string]string, len(levels.modules)),
+	}
+	for module, level := range levels.modules {
+		current.Modules[module] = level.String()
+	}
+	return current
+}
+
+// SetLevel changes the global level, or a module's level when module is
+// given. An empty level removes the module's override.
+func SetLevel(module, level string) error {
+	if module == "" {
+		parsed, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		levels.global.SetLevel(parsed)
+		return nil
+	}
+
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+
+	if level == "" {
+		delete(levels.modules, module)
+		return nil
+	}
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	levels.modules[module] = parsed
+	return nil
+}
+
+// setLevels replaces every level at once, as loaded from config
+func setLevels(level string, modules map[string]string) error {
+	global, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	parsed := make(map[string]zapcore.Level, len(modules))
+	for module, moduleLevel := range modules {
+		l, err := zapcore.ParseLevel(moduleLevel)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+		parsed[module] = l
+	}
+
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	levels.global.SetLevel(global)
+	levels.modules = parsed
+	return nil
+}
+
+// Enabled passes entries at or above the lowest configured level; the
+// module's own level is applied once the caller is known
+func (s *levelSet) Enabled(level zapcore.Level) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lowest := s.global.Level()
+	for _, moduleLevel := range s.modules {
+		if moduleLevel < lowest {
+			lowest = moduleLevel
+		}
+	}
+	return level >= lowest
+}
+
+// allows reports whether entry should be written at its caller's level
+func (s *levelSet) allows(entry zapcore.Entry) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.modules) > 0 && entry.Caller.Defined {
+		if level, ok := s.modules[moduleOf(entry.Caller)]; ok {
+			return entry.Level >= level
+		}
+	}
+	return entry.Level >= s.global.Level()
+}
+
+// moduleOf names the package a caller belongs to
+func moduleOf(caller zapcore.EntryCaller) string {
+	return filepath.Base(filepath.Dir(caller.File))
+}
+
+// levelCore drops entries below their module's level. Entries reach Write
+// only after zap has resolved their caller, which Check cannot see.
+type levelCore struct {
+	zapcore.Core
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields)}
+}
+
+// Check asks the wrapped core first so that sampling still applies, and
+// keeps this core in the chain so Write can filter by module
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Check(entry, nil) == nil {
+		return checked
+	}
+	return checked.AddCore(entry, c)
+}
+
+func (c *levelCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if !levels.allows(entry) {
+		return nil
+	}
+	return c.Core.Write(entry, fields)
+}
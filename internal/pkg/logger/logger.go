@@ -1,51 +1,80 @@
 package logger
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 )
 
 var (
-	logger *zap.Logger
-	once   sync.Once
+	logger  *zap.Logger
+	current *output
+	once    sync.Once
+	mu      sync.Mutex
+
+	// redaction holds the rules applied to every entry. Until main loads the
+	// rules for its environment, everything that can be masked is.
+	redaction atomic.Pointer[redactor]
 )
 
-// InitLogger initializes a singleton zap logger
+type contextKey struct{}
+
+// InitLogger initializes a singleton zap logger with the default options for
+// the environment
 func InitLogger(environment string) *zap.Logger {
 	once.Do(func() {
-		var err error
-		var config zap.Config
-
-		// Different configurations for development and production
-		if environment == "production" {
-			config = zap.NewProductionConfig()
-			config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-			config.OutputPaths = []string{"./logs/app.log"}
-			config.ErrorOutputPaths = []string{"./logs/errors.log"}
-		} else {
-			config = zap.NewDevelopmentConfig()
-			config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		}
-
-		// Custom sampling for high-frequency logs
-		config.Sampling = &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
+		defaults, err := newRedactor(DefaultRedactionRules())
+		if err != nil {
+			panic(err)
 		}
+		redaction.Store(defaults)
 
-		logger, err = config.Build(zap.AddCallerSkip(1))
+		current, err = build(DefaultOptions(environment))
 		if err != nil {
 			panic(err)
 		}
+		logger = current.logger
 	})
 
 	return logger
 }
 
+// Configure rebuilds the logger from opts once config is loaded. It should
+// be called before loggers are derived from the global one, since those keep
+// the outputs and sampling they were derived with.
+func Configure(opts Options) (*zap.Logger, error) {
+	InitLogger(opts.Environment)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	next, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+	previous := current
+	current, logger = next, next.logger
+	previous.close()
+	return logger, nil
+}
+
+// SetRedactionRules replaces the rules masking sensitive values in log
+// output. Fields already attached to derived loggers keep the masking they
+// were added with.
+func SetRedactionRules(rules RedactionRules) error {
+	r, err := newRedactor(rules)
+	if err != nil {
+		return err
+	}
+	redaction.Store(r)
+	return nil
+}
+
 // GetLogger returns the initialized logger
 func GetLogger() *zap.Logger {
 	if logger == nil {
@@ -59,19 +88,62 @@ func WithError(err error) *zap.Logger {
 	return GetLogger().With(zap.Error(err))
 }
 
-// LoggerMiddleware creates a gin middleware for logging requests
+// NewContext returns a copy of ctx carrying a request-scoped logger
+func NewContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// WithContext returns the request-scoped logger carried by ctx, or the global
+// logger outside of requests, with the trace and span IDs of the span in ctx
+// so that log lines can be matched with their trace
+func WithContext(ctx context.Context) *zap.Logger {
+	l, ok := ctx.Value(contextKey{}).(*zap.Logger)
+	if !ok {
+		l = GetLogger()
+	}
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return l
+	}
+	return l.With(
+		zap.String("trace_id", spanContext.TraceID().String()),
+		zap.String("span_id", spanContext.SpanID().String()),
+	)
+}
+
+// AddFields adds fields to the request-scoped logger carried by ctx
+func AddFields(ctx context.Context, fields ...zap.Field) context.Context {
+	l, ok := ctx.Value(contextKey{}).(*zap.Logger)
+	if !ok {
+		l = GetLogger()
+	}
+	return NewContext(ctx, l.With(fields...))
+}
+
+// LoggerMiddleware creates a gin middleware for logging requests. It attaches
+// a logger carrying the request ID and route to the request context, which
+// handlers and services log through.
 func LoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
 		method := c.Request.Method
 
+		ctx := AddFields(
+			c.Request.Context(),
+			zap.String("request_id", c.GetString("requestID")),
+			zap.String("route", c.FullPath()),
+		)
+		c.Request = c.Request.WithContext(ctx)
+
 		// Process request
 		c.Next()
 
-		// Log details after request
+		// Log details after request. The request context now also carries
+		// the user ID if the request was authenticated.
 		duration := time.Since(start)
-		logger := GetLogger().With(
+		logger := WithContext(c.Request.Context()).With(
 			zap.String("method", method),
 			zap.String("path", path),
 			zap.Int("status", c.Writer.Status()),
@@ -0,0 +1,186 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Options configure where and how much the logger writes
+type Options struct {
+	Environment string
+
+	// Level is the global level and Modules override it per package, such as
+	// "services" or "handlers"
+	Level   string
+	Modules map[string]string
+
+	// Outputs are "stdout", "stderr" or file paths. ErrorOutput receives the
+	// logger's own errors.
+	Outputs     []string
+	ErrorOutput string
+
+	Rotation Rotation
+	Sampling Sampling
+}
+
+// Rotation applies to file outputs. Files are rotated when they reach
+// MaxSizeMB and, if Interval is set, every Interval. Rotated files are kept
+// for MaxAgeDays and at most MaxBackups of them; zero keeps them all.
+type Rotation struct {
+	MaxSizeMB  int
+	MaxAgeDays int
+	MaxBackups int
+	Compress   bool
+	Interval   time.Duration
+}
+
+// Sampling logs the first Initial entries with the same level and message
+// in each Tick, then every Thereafter-th. Sampling is off when Initial is 0.
+type Sampling struct {
+	Initial    int
+	Thereafter int
+	Tick       time.Duration
+}
+
+// DefaultOptions returns the options used before config is loaded
+func DefaultOptions(environment string) Options {
+	if environment == "production" {
+		return Options{
+			Environment: environment,
+			Level:       "info",
+			Outputs:     []string{"./logs/app.log"},
+			ErrorOutput: "./logs/errors.log",
+			Rotation:    Rotation{MaxSizeMB: 100, MaxAgeDays: 14, MaxBackups: 10, Compress: true},
+			Sampling:    Sampling{Initial: 100, Thereafter: 100, Tick: time.Second},
+		}
+	}
+	return Options{
+		Environment: environment,
+		Level:       "debug",
+		Outputs:     []string{"stdout"},
+		ErrorOutput: "stderr",
+		Sampling:    Sampling{Initial: 100, Thereafter: 100, Tick: time.Second},
+	}
+}
+
+// output is a built logger and the files it writes to
+type output struct {
+	logger *zap.Logger
+	files  []*lumberjack.Logger
+	stop   chan struct{}
+}
+
+func build(opts Options) (*output, error) {
+	if err := setLevels(opts.Level, opts.Modules); err != nil {
+		return nil, fmt.Errorf("invalid log level: %w", err)
+	}
+
+	var encoder zapcore.Encoder
+	if opts.Environment == "production" {
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	} else {
+		encoderConfig := zap.NewDevelopmentEncoderConfig()
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	}
+
+	out := &output{}
+	writers := make([]zapcore.WriteSyncer, 0, len(opts.Outputs))
+	for _, path := range opts.Outputs {
+		writer, err := out.open(path, opts.Rotation)
+		if err != nil {
+			return nil, err
+		}
+		writers = append(writers, writer)
+	}
+	errorOutput, err := out.open(opts.ErrorOutput, opts.Rotation)
+	if err != nil {
+		return nil, err
+	}
+
+	var core zapcore.Core = zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(writers...), levels)
+	if opts.Sampling.Initial > 0 {
+		tick := opts.Sampling.Tick
+		if tick <= 0 {
+			tick = time.Second
+		}
+		core = zapcore.NewSamplerWithOptions(core, tick, opts.Sampling.Initial, opts.Sampling.Thereafter)
+	}
+	core = newRedactingCore(&levelCore{Core: core}, &redaction)
+
+	zapOpts := []zap.Option{
+		zap.AddCaller(),
+		zap.AddStacktrace(zapcore.ErrorLevel),
+		zap.ErrorOutput(errorOutput),
+	}
+	if opts.Environment != "production" {
+		zapOpts = append(zapOpts, zap.Development())
+	}
+	out.logger = zap.New(core, zapOpts...)
+
+	if opts.Rotation.Interval > 0 && len(out.files) > 0 {
+		out.stop = make(chan struct{})
+		go out.rotateEvery(opts.Rotation.Interval)
+	}
+	return out, nil
+}
+
+// open returns a writer for stdout, stderr or a rotated file
+func (o *output) open(path string, rotation Rotation) (zapcore.WriteSyncer, error) {
+	switch path {
+	case "", "stderr":
+		return zapcore.Lock(os.Stderr), nil
+	case "stdout":
+		return zapcore.Lock(os.Stdout), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+	file := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    rotation.MaxSizeMB,
+		MaxAge:     rotation.MaxAgeDays,
+		MaxBackups: rotation.MaxBackups,
+		Compress:   rotation.Compress,
+	}
+	o.files = append(o.files, file)
+	return zapcore.AddSync(file), nil
+}
+
+func (o *output) rotateEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-ticker.C:
+			for _, file := range o.files {
+				if err := file.Rotate(); err != nil {
+					o.logger.Error("Log rotation failed", zap.String("file", file.Filename), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// close stops rotation and closes the files once the logger is replaced
+func (o *output) close() {
+	if o.stop != nil {
+		close(o.stop)
+	}
+	_ = o.logger.Sync()
+	for _, file := range o.files {
+		_ = file.Close()
+	}
+}
@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactedValue replaces masked values in log output
+const redactedValue = "[REDACTED]"
+
+// Built-in patterns that rules can refer to by name
+var builtinPatterns = map[string]*regexp.Regexp{
+	// Phone numbers in international format
+	"e164": regexp.MustCompile(`\+[1-9]\d{6,14}`),
+	// Signed or unsigned JWTs, including DPoP proofs
+	"jwt": regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	// Secrets from utils.GenerateRandomToken(32) and hashes of them. Shorter
+	// hex strings are IDs, such as request and trace IDs, and are kept.
+	"hex_token": regexp.MustCompile(`\b[0-9a-fA-F]{40,}\b`),
+}
+
+// DefaultRedactedFields are masked whatever their value
+var DefaultRedactedFields = []string{
+	"password",
+	"new_password",
+	"password_hash",
+	"secret",
+	"client_secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"authorization",
+	"api_key",
+	"otp_code",
+	"mobile_number",
+	"email",
+}
+
+// RedactionRules select what is masked before log entries are written
+type RedactionRules struct {
+	// Fields are masked entirely, matched case-insensitively by key at any
+	// depth of a structured field
+	Fields []string
+	// Patterns are masked wherever they occur in messages, string values,
+	// errors and stack traces. Each is the name of a built-in pattern (e164,
+	// jwt, hex_token) or a regular expression.
+	Patterns []string
+}
+
+// DefaultRedactionRules masks every default field and built-in pattern
+func DefaultRedactionRules() RedactionRules {
+	patterns := make([]string, 0, len(builtinPatterns))
+	for name := range builtinPatterns {
+		patterns = append(patterns, name)
+	}
+	return RedactionRules{
+		Fields:   DefaultRedactedFields,
+		Patterns: patterns,
+	}
+}
+
+// redactor is compiled from RedactionRules
+type redactor struct {
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+}
+
+func newRedactor(rules RedactionRules) (*redactor, error) {
+	r := &redactor{fields: make(map[string]bool, len(rules.Fields))}
+	for _, field := range rules.Fields {
+		r.fields[strings.ToLower(field)] = true
+	}
+	for _, pattern := range rules.Patterns {
+		if re, ok := builtinPatterns[pattern]; ok {
+			r.patterns = append(r.patterns, re)
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+func (r *redactor) maskKey(key string) bool {
+	return r.fields[strings.ToLower(key)]
+}
+
+func (r *redactor) string(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, redactedValue)
+	}
+	return s
+}
+
+// field returns a copy of f that is safe to write
+func (r *redactor) field(f zapcore.Field) zapcore.Field {
+	if r.maskKey(f.Key) {
+		return zap.String(f.Key, redactedValue)
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.string(f.String)
+		return f
+	case zapcore.ByteStringType:
+		return zap.String(f.Key, r.string(string(f.Interface.([]byte))))
+	case zapcore.ErrorType:
+		err, ok := f.Interface.(error)
+		if !ok || err == nil {
+			return f
+		}
+		return zap.String(f.Key, r.string(err.Error()))
+	case zapcore.StringerType:
+		stringer, ok := f.Interface.(fmt.Stringer)
+		if !ok || stringer == nil {
+			return f
+		}
+		return zap.String(f.Key, r.string(stringer.String()))
+	case zapcore.ReflectType:
+		// Round-trip through JSON so nested keys and strings can be checked
+		data, err := json.Marshal(f.Interface)
+		if err != nil {
+			return zap.String(f.Key, r.string(fmt.Sprint(f.Interface)))
+		}
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return zap.String(f.Key, r.string(string(data)))
+		}
+		return zap.Any(f.Key, r.value(value))
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.InlineMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if f.Type == zapcore.InlineMarshalerType {
+			return zap.Any(f.Key, r.value(enc.Fields))
+		}
+		return zap.Any(f.Key, r.value(enc.Fields[f.Key]))
+	}
+	return f
+}
+
+// value masks keys and patterns in a decoded structured value
+func (r *redactor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.string(v)
+	case map[string]interface{}:
+		for key, nested := range v {
+			if r.maskKey(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = r.value(nested)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = r.value(nested)
+		}
+		return v
+	}
+	return v
+}
+
+func (r *redactor) fieldList(fields []zapcore.Field) []zapcore.Field {
+	safe := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		safe[i] = r.field(f)
+	}
+	return safe
+}
+
+// redactingCore masks fields and patterns before entries reach the wrapped
+// core. The rules are shared by every logger derived from it, so they can be
+// replaced after startup.
+type redactingCore struct {
+	zapcore.Core
+	rules *atomic.Pointer[redactor]
+}
+
+func newRedactingCore(core zapcore.Core, rules *atomic.Pointer[redactor]) zapcore.Core {
+	return &redactingCore{Core: core, rules: rules}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{
+		Core:  c.Core.With(c.rules.Load().fieldList(fields)),
+		rules: c.rules,
+	}
+}
+
+// Check asks the wrapped core first so that its level and sampling decisions
+// still apply
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Check(entry, nil) == nil {
+		return checked
+	}
+	return checked.AddCore(entry, c)
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	r := c.rules.Load()
+	entry.Message = r.string(entry.Message)
+	entry.Stack = r.string(entry.Stack)
+	return c.Core.Write(entry, r.fieldList(fields))
+}
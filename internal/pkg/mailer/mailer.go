@@ -0,0 +1,52 @@
+// Package mailer sends plain text email through an SMTP server. In
+// development it can point at a local SMTP stand-in such as MailHog or
+// Mailpit, which accept mail without authentication.
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mailer sends messages from a fixed sender address
+type Mailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// New returns a mailer for the SMTP server at host:port. Authentication is
+// only used when a username is set; net/smtp refuses to send credentials
+// over an unencrypted connection to anything but localhost.
+func New(host string, port int, username, password, from string) *Mailer {
+	m := &Mailer{
+		addr: net.JoinHostPort(host, fmt.Sprint(port)),
+		from: from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send delivers a plain text message to a single recipient
+func (m *Mailer) Send(to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("mailer: header values must not contain line breaks")
+	}
+
+	headers := []string{
+		"From: " + m.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	msg := strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(body, "\n", "\r\n")
+
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg))
+}
@@ -0,0 +1,115 @@
+// Package metrics defines the Prometheus metrics exported on /metrics. They
+// are registered with the default registry, which also carries the Go runtime
+// and process collectors.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "greeneye_user"
+
+var (
+	// HTTPRequestDuration observes request latency by route template and status
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Registrations counts completed sign-ups
+	Registrations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Users registered.",
+	})
+
+	// Logins counts password logins by result, and failures by reason
+	Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Password logins by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	// OTPsSent counts one-time codes and links sent to users
+	OTPsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "otps_sent_total",
+		Help:      "One-time codes and links sent, by purpose and channel.",
+	}, []string{"purpose", "channel"})
+
+	// PasswordResets counts password resets requested and completed
+	PasswordResets = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "password_resets_total",
+		Help:      "Password resets by stage.",
+	}, []string{"stage"})
+
+	// RateLimitRejections counts requests turned away by a rate limit
+	RateLimitRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by rate limiting, by limiter.",
+	}, []string{"limiter"})
+
+	// CacheRequests counts cache lookups by cache and result
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Cache lookups by cache and result (hit, miss or bypass).",
+	}, []string{"cache", "result"})
+
+	// PasswordHashQueueWait observes how long hashes waited for a worker
+	PasswordHashQueueWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "password_hash_queue_wait_seconds",
+		Help:      "Time password hashes waited for a hashing slot.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	})
+
+	// PasswordHashRejections counts hashes shed because the queue was full
+	PasswordHashRejections = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "password_hash_rejections_total",
+		Help:      "Password hashes rejected because the hashing queue was full.",
+	})
+
+	// RedisUp is 1 while Redis is reachable and 0 while running degraded
+	RedisUp = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "redis_up",
+		Help:      "Whether Redis is reachable (1) or the service is running degraded (0).",
+	})
+
+	// RedisDegradedRequests counts operations that hit a Redis outage, by
+	// feature and whether the feature failed open or closed
+	RedisDegradedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redis_degraded_requests_total",
+		Help:      "Operations affected by a Redis outage, by feature and policy (open or closed).",
+	}, []string{"feature", "policy"})
+)
+
+// Login results and failure reasons
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+
+	LoginUnknownUser     = "unknown_user"
+	LoginWrongPassword   = "wrong_password"
+	LoginAccountInactive = "account_inactive"
+	LoginBusy            = "busy"
+	LoginError           = "error"
+)
+
+// Cache names and lookup results
+const (
+	CacheUsers     = "users"
+	CacheResponses = "responses"
+
+	CacheHit    = "hit"
+	CacheMiss   = "miss"
+	CacheBypass = "bypass"
+)
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var (
+	mongoConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mongo_pool_connections",
+		Help:      "MongoDB pool connections by server and state (open or in_use).",
+	}, []string{"address", "state"})
+
+	mongoCheckoutFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mongo_pool_checkout_failures_total",
+		Help:      "Failed MongoDB connection checkouts by server and reason.",
+	}, []string{"address", "reason"})
+)
+
+// MongoPoolMonitor returns a pool monitor that tracks MongoDB connection
+// pool usage. Set it on the client options before connecting.
+func MongoPoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionCreated:
+				mongoConnections.WithLabelValues(e.Address, "open").Inc()
+			case event.ConnectionClosed:
+				mongoConnections.WithLabelValues(e.Address, "open").Dec()
+			case event.GetSucceeded:
+				mongoConnections.WithLabelValues(e.Address, "in_use").Inc()
+			case event.ConnectionReturned:
+				mongoConnections.WithLabelValues(e.Address, "in_use").Dec()
+			case event.GetFailed:
+				mongoCheckoutFailures.WithLabelValues(e.Address, e.Reason).Inc()
+			}
+		},
+	}
+}
+
+// redisPoolCollector reads the go-redis pool statistics on each scrape
+type redisPoolCollector struct {
+	client *redis.Client
+
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+	staleConns *prometheus.Desc
+}
+
+// RegisterRedisPool exports the connection pool statistics of a Redis client
+func RegisterRedisPool(client *redis.Client) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", name), help, nil, nil)
+	}
+
+	prometheus.MustRegister(&redisPoolCollector{
+		client:     client,
+		hits:       desc("hits_total", "Times a free connection was found in the Redis pool."),
+		misses:     desc("misses_total", "Times no free connection was found in the Redis pool."),
+		timeouts:   desc("timeouts_total", "Times waiting for a Redis connection timed out."),
+		totalConns: desc("connections", "Connections in the Redis pool."),
+		idleConns:  desc("idle_connections", "Idle connections in the Redis pool."),
+		staleConns: desc("stale_connections_total", "Stale connections removed from the Redis pool."),
+	})
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.staleConns
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(stats.StaleConns))
+}
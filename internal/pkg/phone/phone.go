@@ -0,0 +1,160 @@
+// Package phone parses user supplied phone numbers into canonical E.164 form
+// using libphonenumber's per-country metadata.
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// Number types
+const (
+	TypeMobile           = "mobile"
+	TypeLandline         = "landline"
+	TypeLandlineOrMobile = "landline_or_mobile"
+	TypeVoIP             = "voip"
+	TypeTollFree         = "toll_free"
+	TypeOther            = "other"
+)
+
+var (
+	// ErrInvalidNumber is returned for input that is not a valid phone number
+	ErrInvalidNumber = errors.New("not a valid phone number")
+	// ErrUnknownCountry is returned for a country code with no numbering plan
+	ErrUnknownCountry = errors.New("unknown country code")
+	// ErrCountryMismatch is returned when the number belongs to another country
+	ErrCountryMismatch = errors.New("country code does not match the phone number")
+)
+
+// Number is a parsed and validated phone number
+type Number struct {
+	// E164 is the canonical form, e.g. +919876543210
+	E164 string
+	// Region is the ISO 3166-1 alpha-2 region the number belongs to
+	Region string
+	// CountryCode is the calling code with a leading plus, e.g. +91
+	CountryCode string
+	// Type is one of the Type constants
+	Type string
+}
+
+// Parse validates a number given with the user's country, which may be an
+// ISO region ("IN") or a calling code ("+91" or "91"). Numbers in national
+// format are interpreted in that country, and numbers in international
+// format must belong to it.
+func Parse(raw, country string) (*Number, error) {
+	region, callingCode, err := resolveCountry(country)
+	if err != nil {
+		return nil, err
+	}
+
+	number, err := parse(raw, region)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(number.GetCountryCode()) != callingCode {
+		return nil, ErrCountryMismatch
+	}
+	// Calling codes shared by several regions, like +1, need the region
+	// itself to match when one was given
+	if isRegion(country) && !phonenumbers.IsValidNumberForRegion(number, region) {
+		return nil, ErrCountryMismatch
+	}
+
+	return describe(number), nil
+}
+
+// Normalize returns the canonical E.164 form of a number for lookups. The
+// country is optional: without it the number must be in international
+// format, with it the number is read as Parse reads it.
+func Normalize(raw, country string) (string, error) {
+	if strings.TrimSpace(country) != "" {
+		number, err := Parse(raw, country)
+		if err != nil {
+			return "", err
+		}
+		return number.E164, nil
+	}
+
+	number, err := parse(raw, "")
+	if err != nil {
+		return "", err
+	}
+	return phonenumbers.Format(number, phonenumbers.E164), nil
+}
+
+func parse(raw, region string) (*phonenumbers.PhoneNumber, error) {
+	number, err := phonenumbers.Parse(strings.TrimSpace(raw), region)
+	if err != nil || !phonenumbers.IsValidNumber(number) {
+		return nil, ErrInvalidNumber
+	}
+	return number, nil
+}
+
+func describe(number *phonenumbers.PhoneNumber) *Number {
+	return &Number{
+		E164:        phonenumbers.Format(number, phonenumbers.E164),
+		Region:      phonenumbers.GetRegionCodeForNumber(number),
+		CountryCode: fmt.Sprintf("+%d", number.GetCountryCode()),
+		Type:        numberType(phonenumbers.GetNumberType(number)),
+	}
+}
+
+// resolveCountry returns the default region and calling code for a country
+// given as a region or a calling code
+func resolveCountry(country string) (string, int, error) {
+	country = strings.TrimSpace(country)
+
+	if isRegion(country) {
+		region := strings.ToUpper(country)
+		callingCode := phonenumbers.GetCountryCodeForRegion(region)
+		if callingCode == 0 {
+			return "", 0, ErrUnknownCountry
+		}
+		return region, callingCode, nil
+	}
+
+	callingCode, err := strconv.Atoi(strings.TrimPrefix(country, "+"))
+	if err != nil || callingCode <= 0 {
+		return "", 0, ErrUnknownCountry
+	}
+	region := phonenumbers.GetRegionCodeForCountryCode(callingCode)
+	if region == "ZZ" {
+		return "", 0, ErrUnknownCountry
+	}
+	return region, callingCode, nil
+}
+
+func isRegion(country string) bool {
+	country = strings.TrimSpace(country)
+	if len(country) != 2 {
+		return false
+	}
+	for _, r := range country {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+func numberType(t phonenumbers.PhoneNumberType) string {
+	switch t {
+	case phonenumbers.MOBILE:
+		return TypeMobile
+	case phonenumbers.FIXED_LINE:
+		return TypeLandline
+	case phonenumbers.FIXED_LINE_OR_MOBILE:
+		return TypeLandlineOrMobile
+	case phonenumbers.VOIP:
+		return TypeVoIP
+	case phonenumbers.TOLL_FREE:
+		return TypeTollFree
+	}
+	return TypeOther
+}
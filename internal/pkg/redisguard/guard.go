@@ -0,0 +1,260 @@
+// Package redisguard keeps the service running when Redis is unreachable.
+//
+// A Guard watches the Redis client. Once a command fails because Redis cannot
+// be reached, later commands fail at once with ErrUnavailable instead of each
+// waiting for a timeout, and a background loop pings Redis until it answers
+// again.
+//
+// Each Redis-backed feature has a policy for outages. Features that fail open
+// carry on without Redis:
+//   - rate_limit counts requests in process, per instance
+//   - response_cache and user_cache read from MongoDB
+//   - sessions issues tokens without adding them to the session index, so
+//     signing out everywhere misses them; they can still be revoked one by one
+//
+// Features that fail closed answer 503:
+//   - token_revocation, since a revoked token or blocked user could otherwise
+//     be let in
+//   - dpop_replay, since a replayed proof could otherwise be accepted
+//
+// Password reset, email verification, QR code login and session listing
+// keep their state only in Redis, so they always fail closed.
+package redisguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	apperrors "github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/metrics"
+)
+
+// ErrUnavailable is returned by Redis commands while Redis is unreachable
+var ErrUnavailable = apperrors.New(http.StatusServiceUnavailable, "Service temporarily unavailable, try again later")
+
+// Policy says what a feature does while Redis is unreachable
+type Policy string
+
+const (
+	FailOpen   Policy = "open"
+	FailClosed Policy = "closed"
+)
+
+// Features with a configurable policy
+const (
+	FeatureRateLimit       = "rate_limit"
+	FeatureResponseCache   = "response_cache"
+	FeatureUserCache       = "user_cache"
+	FeatureSessions        = "sessions"
+	FeatureTokenRevocation = "token_revocation"
+	FeatureDPoPReplay      = "dpop_replay"
+)
+
+// DefaultPolicies apply to features config does not mention
+var DefaultPolicies = map[string]Policy{
+	FeatureRateLimit:       FailOpen,
+	FeatureResponseCache:   FailOpen,
+	FeatureUserCache:       FailOpen,
+	FeatureSessions:        FailOpen,
+	FeatureTokenRevocation: FailClosed,
+	FeatureDPoPReplay:      FailClosed,
+}
+
+// probeTimeout bounds each ping of the reconnect loop
+const probeTimeout = 2 * time.Second
+
+// Guard tracks whether Redis is reachable and applies the outage policies
+type Guard struct {
+	client   *redis.Client
+	policies map[string]Policy
+	interval time.Duration
+	up       atomic.Bool
+}
+
+// New installs a guard on client. policies override DefaultPolicies by
+// feature name. Redis is treated as unreachable until Connect succeeds.
+func New(client *redis.Client, policies map[string]string, interval time.Duration) (*Guard, error) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	g := &Guard{
+		client:   client,
+		policies: make(map[string]Policy, len(DefaultPolicies)),
+		interval: interval,
+	}
+	for feature, policy := range DefaultPolicies {
+		g.policies[feature] = policy
+	}
+	for feature, policy := range policies {
+		if _, ok := DefaultPolicies[feature]; !ok {
+			return nil, fmt.Errorf("unknown Redis feature %q", feature)
+		}
+		switch p := Policy(policy); p {
+		case FailOpen, FailClosed:
+			g.policies[feature] = p
+		default:
+			return nil, fmt.Errorf("invalid Redis policy %q for %s", policy, feature)
+		}
+	}
+
+	metrics.RedisUp.Set(0)
+	client.AddHook(g)
+	return g, nil
+}
+
+// Client returns the guarded client
+func (g *Guard) Client() *redis.Client {
+	return g.client
+}
+
+// Available reports whether Redis answered the last time it was used
+func (g *Guard) Available() bool {
+	return g.up.Load()
+}
+
+// Connect pings Redis once. The service starts degraded when it fails.
+func (g *Guard) Connect(ctx context.Context) error {
+	return g.probe(ctx)
+}
+
+// Check pings Redis for readiness reports
+func (g *Guard) Check(ctx context.Context) error {
+	return g.probe(ctx)
+}
+
+// Run pings Redis every interval until ctx is done, marking it available
+// again once it answers
+func (g *Guard) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = g.probe(ctx)
+		}
+	}
+}
+
+// Degrade applies feature's policy to an error from Redis. It returns nil
+// if Redis is unreachable and the feature fails open, in which case the
+// caller carries on without Redis. Other errors are returned unchanged.
+func (g *Guard) Degrade(feature string, err error) error {
+	if !errors.Is(err, ErrUnavailable) {
+		return err
+	}
+
+	policy := g.policies[feature]
+	metrics.RedisDegradedRequests.WithLabelValues(feature, string(policy)).Inc()
+	if policy == FailOpen {
+		return nil
+	}
+	return ErrUnavailable
+}
+
+func (g *Guard) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	if err := g.client.Ping(ctx).Err(); err != nil {
+		g.markDown(err)
+		return err
+	}
+	g.markUp()
+	return nil
+}
+
+func (g *Guard) markUp() {
+	if g.up.CompareAndSwap(false, true) {
+		metrics.RedisUp.Set(1)
+		logger.GetLogger().Info("Redis available")
+	}
+}
+
+func (g *Guard) markDown(err error) {
+	if g.up.CompareAndSwap(true, false) {
+		metrics.RedisUp.Set(0)
+		logger.GetLogger().Warn("Redis unavailable, running degraded", zap.Error(err))
+	}
+}
+
+// isOutage reports whether err means Redis could not be reached, as opposed
+// to an error reply or a caller giving up. A timeout only counts when it
+// comes from the client's own dial, read or write timeouts, not from the
+// deadline of the caller's ctx.
+func isOutage(ctx context.Context, err error) bool {
+	if err == nil ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, context.Canceled) ||
+		ctx.Err() != nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// DialHook implements redis.Hook
+func (g *Guard) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook fails commands at once while Redis is unreachable, except the
+// pings that detect it is back
+func (g *Guard) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "ping" {
+			return next(ctx, cmd)
+		}
+		if !g.Available() {
+			cmd.SetErr(ErrUnavailable)
+			return ErrUnavailable
+		}
+
+		err := next(ctx, cmd)
+		if isOutage(ctx, err) {
+			g.markDown(err)
+			cmd.SetErr(ErrUnavailable)
+			return ErrUnavailable
+		}
+		return err
+	}
+}
+
+// ProcessPipelineHook does the same for pipelines and transactions
+func (g *Guard) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !g.Available() {
+			failAll(cmds)
+			return ErrUnavailable
+		}
+
+		err := next(ctx, cmds)
+		if isOutage(ctx, err) {
+			g.markDown(err)
+			failAll(cmds)
+			return ErrUnavailable
+		}
+		return err
+	}
+}
+
+func failAll(cmds []redis.Cmder) {
+	for _, cmd := range cmds {
+		cmd.SetErr(ErrUnavailable)
+	}
+}
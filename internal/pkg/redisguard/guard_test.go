@@ -0,0 +1,127 @@
+package redisguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestIsOutage(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	readTimeout := &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, stop := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer stop()
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"no error", context.Background(), nil, false},
+		{"key not found", context.Background(), redis.Nil, false},
+		{"error reply", context.Background(), redis.TxFailedErr, false},
+		{"connection refused", context.Background(), refused, true},
+		{"client read timeout", context.Background(), readTimeout, true},
+		{"connection closed", context.Background(), io.EOF, true},
+		{"truncated reply", context.Background(), fmt.Errorf("reading reply: %w", io.ErrUnexpectedEOF), true},
+		{"caller deadline", context.Background(), context.DeadlineExceeded, false},
+		{"caller cancelled", context.Background(), context.Canceled, false},
+		{"wrapped caller deadline", context.Background(), fmt.Errorf("get: %w", context.DeadlineExceeded), false},
+		// The caller's deadline may surface as an i/o timeout on the socket
+		{"read timeout after caller deadline", expired, readTimeout, false},
+		{"connection closed after caller cancelled", cancelled, io.EOF, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isOutage(tc.ctx, tc.err); got != tc.want {
+				t.Errorf("isOutage(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGuardOutage(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+
+	g, err := New(client, map[string]string{FeatureSessions: string(FailClosed)}, time.Minute)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if g.Available() {
+		t.Fatal("Available before Connect")
+	}
+	if err := g.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	// A caller giving up does not take Redis down for everyone else
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.Get(ctx, "key").Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Get with cancelled context error = %v, want %v", err, context.Canceled)
+	}
+	if !g.Available() {
+		t.Fatal("cancelled context marked Redis unavailable")
+	}
+
+	server.Close()
+	if err := client.Get(context.Background(), "key").Err(); err != ErrUnavailable {
+		t.Fatalf("Get during outage error = %v, want %v", err, ErrUnavailable)
+	}
+	if g.Available() {
+		t.Fatal("Available after Redis went away")
+	}
+
+	cases := []struct {
+		feature string
+		want    error
+	}{
+		{FeatureResponseCache, nil},
+		{FeatureSessions, ErrUnavailable},
+		{FeatureTokenRevocation, ErrUnavailable},
+	}
+	for _, tc := range cases {
+		if got := g.Degrade(tc.feature, ErrUnavailable); got != tc.want {
+			t.Errorf("Degrade(%s) = %v, want %v", tc.feature, got, tc.want)
+		}
+	}
+
+	if err := server.Restart(); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	if err := g.Check(context.Background()); err != nil {
+		t.Fatalf("Check after restart: %v", err)
+	}
+	if err := client.Set(context.Background(), "key", "value", 0).Err(); err != nil {
+		t.Fatalf("Set after restart: %v", err)
+	}
+}
+
+func TestNewRejectsUnknownPolicies(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	t.Cleanup(func() { client.Close() })
+
+	for _, policies := range []map[string]string{
+		{"unknown_feature": string(FailOpen)},
+		{FeatureRateLimit: "sometimes"},
+	} {
+		if _, err := New(client, policies, 0); err == nil {
+			t.Errorf("New(%v) accepted invalid policies", policies)
+		}
+	}
+}
@@ -1,14 +0,0 @@
-package utils
-
-import "golang.org/x/crypto/bcrypt"
-
-// HashPassword hashes the plain password
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
-}
-
-// CheckPasswordHash verifies the password against the hash
-func CheckPasswordHash(password, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-}
\ No newline at end of file
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 )
 
@@ -13,4 +14,11 @@ func GenerateRandomToken(nBytes int) string {
 		return ""
 	}
 	return hex.EncodeToString(bytes)
-}
\ No newline at end of file
+}
+
+// HashToken returns the hex SHA-256 digest of a high-entropy token, suitable
+// for storage and lookup
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
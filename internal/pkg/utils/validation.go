@@ -9,4 +9,9 @@ var validate = validator.New()
 // ValidateStruct validates a struct using validator tags
 func ValidateStruct(obj interface{}) error {
 	return validate.Struct(obj)
-}
\ No newline at end of file
+}
+
+// ValidateVar validates a single value against a validator tag
+func ValidateVar(value interface{}, tag string) error {
+	return validate.Var(value, tag)
+}
@@ -0,0 +1,33 @@
+// Package version holds build information injected by the linker, e.g.
+//
+//	go build -ldflags "-X github.com/greeneye-foundation/greeneye-be-user/internal/pkg/version.Commit=$(git rev-parse HEAD)"
+//
+// See the build target in the Makefile.
+package version
+
+import "runtime"
+
+// Set at build time with -ldflags "-X ..."
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
@@ -2,17 +2,20 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 
 	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/handlers"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/middleware"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
 )
 
-func authRoutes(r *gin.RouterGroup, userService *services.UserService, cfg *config.Config, redisClient *redis.Client) {
-	authService := services.NewAuthService(userService, cfg, redisClient)
-	authHandler := handlers.NewAuthHandler(authService, cfg, redisClient)
+func authRoutes(r *gin.RouterGroup, userService *services.UserService, apiKeyService *services.APIKeyService, auditService *services.AuditService, consentService *services.ConsentService, emailService *services.EmailService, cfg *config.Config, redisGuard *redisguard.Guard) {
+	redisClient := redisGuard.Client()
+	tokenService := services.NewTokenService(cfg, redisGuard)
+	dpopService := services.NewDPoPService(cfg, redisGuard)
+	authService := services.NewAuthService(userService, tokenService, auditService, consentService, emailService, cfg, redisClient)
+	authHandler := handlers.NewAuthHandler(authService, dpopService, cfg, redisClient)
 
 	authGroup := r.Group("/auth")
 	{
@@ -24,7 +27,7 @@ func authRoutes(r *gin.RouterGroup, userService *services.UserService, cfg *conf
 
 	// Protected routes example
 	protected := r.Group("/protected")
-	protected.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+	protected.Use(middleware.AuthMiddleware(tokenService, apiKeyService, dpopService))
 	{
 		protected.GET("/profile", authHandler.Profile)
 	}
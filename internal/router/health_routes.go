@@ -1,23 +1,60 @@
 package router
 
 import (
+	"context"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/health"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/version"
 )
 
+// newHealthChecker checks the service's dependencies
+func (r *Router) newHealthChecker() *health.Checker {
+	checker := health.NewChecker(time.Duration(r.config.Health.CheckTimeout) * time.Millisecond)
+	checker.Add("mongodb", func(ctx context.Context) error {
+		return r.db.Ping(ctx, readpref.Primary())
+	})
+	// Without Redis the service runs degraded rather than not at all
+	checker.AddOptional("redis", r.services.Redis.Check)
+	return checker
+}
+
 func (r *Router) setupHealthRoutes() {
 	health := r.router.Group("/health")
 
-	health.GET("/", func(c *gin.Context) {
+	// Liveness: the process is up and serving requests. It does not check
+	// dependencies, so an outage does not get every instance restarted.
+	live := func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status": "ok",
 			"time":   time.Now(),
 		})
-	})
+	}
+	health.GET("/", live)
+	health.GET("/live", live)
 
 	health.GET("/ping", func(c *gin.Context) {
 		c.String(http.StatusOK, "pong")
 	})
-}
\ No newline at end of file
+
+	// Readiness: MongoDB answers and the server is not shutting down. A Redis
+	// outage is reported as degraded but still ready.
+	health.GET("/ready", func(c *gin.Context) {
+		report := r.health.Check(c.Request.Context())
+
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(status, report)
+	})
+
+	r.router.GET("/version", func(c *gin.Context) {
+		c.JSON(http.StatusOK, version.Get())
+	})
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// setupMetricsRoutes serves /metrics on the API port, unless it is served on
+// a separate admin port by main
+func (r *Router) setupMetricsRoutes() {
+	if !r.config.Metrics.Enabled || r.config.Metrics.AdminPort != "" {
+		return
+	}
+
+	r.router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+}
@@ -6,21 +6,30 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/handlers"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/middleware"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/health"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/tracing"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/services"
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 type Router struct {
-	router *gin.Engine
-	config *config.Config
-	db     *mongo.Client
-	redis  *redis.Client
+	router   *gin.Engine
+	config   *config.Config
+	db       *mongo.Client
+	redis    *redis.Client
+	services *services.Registry
+	health   *health.Checker
 }
 
 func NewRouter(
 	cfg *config.Config,
 	db *mongo.Client,
 	redisClient *redis.Client,
+	svc *services.Registry,
 ) *Router {
 	// Set Gin mode based on environment
 	if cfg.Server.Environment == "production" {
@@ -33,20 +42,22 @@ func NewRouter(
 	router := gin.New()
 
 	// Add middleware
-	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
 	// Create Router struct
 	r := &Router{
-		router: router,
-		config: cfg,
-		db:     db,
-		redis:  redisClient,
+		router:   router,
+		config:   cfg,
+		db:       db,
+		redis:    redisClient,
+		services: svc,
 	}
+	r.health = r.newHealthChecker()
 
 	// Setup routes
 	r.setupMiddleware()
 	r.setupHealthRoutes()
+	r.setupMetricsRoutes()
 	r.setupRoutes()
 
 	return r
@@ -54,12 +65,16 @@ func NewRouter(
 
 func (r *Router) setupRoutes() {
 	// Setup main application routes
-	handlers.SetupRoutes(r.router, r.db, r.config, r.redis)
+	handlers.SetupRoutes(r.router, r.services, r.config, r.redis)
 }
 
 func (r *Router) setupMiddleware() {
 	// Add any global middleware
-	// r.router.Use(someMiddleware())
+	r.router.Use(middleware.RequestIDMiddleware())
+	r.router.Use(otelgin.Middleware(tracing.ServiceName(r.config)))
+	r.router.Use(logger.LoggerMiddleware())
+	r.router.Use(middleware.MetricsMiddleware())
+	r.router.Use(middleware.ErrorFieldsMiddleware(middleware.RequestIDField, middleware.TraceIDField))
 }
 
 func (r *Router) Start() error {
@@ -67,6 +82,11 @@ func (r *Router) Start() error {
 	return r.router.Run(fmt.Sprintf(":%s", r.config.Server.Port))
 }
 
+// Health returns the readiness checker, which main marks as shutting down
+func (r *Router) Health() *health.Checker {
+	return r.health
+}
+
 // Optional: Method to get the underlying Gin engine
 func (r *Router) Engine() *gin.Engine {
 	return r.router
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+)
+
+var (
+	// ErrDeletionAlreadyRequested is returned when a purge is already scheduled
+	ErrDeletionAlreadyRequested = errors.New(http.StatusConflict, "Account deletion has already been requested")
+	// ErrNoPendingDeletion is returned when restoring an account that is not pending deletion
+	ErrNoPendingDeletion = errors.New(http.StatusConflict, "Account is not pending deletion")
+	// ErrInvalidCredentials is returned when a password re-confirmation fails
+	ErrInvalidCredentials = errors.New(http.StatusUnauthorized, "Invalid credentials")
+)
+
+// AccountService handles self-service account deletion: a deletion request
+// starts a grace period during which the user may restore the account, after
+// which a background job purges it.
+type AccountService struct {
+	userService    *UserService
+	tokenService   *TokenService
+	apiKeyService  *APIKeyService
+	auditService   *AuditService
+	statusService  *StatusService
+	authService    *AuthService
+	emailService   *EmailService
+	exportService  *ExportService
+	consentService *ConsentService
+	handleService  *HandleService
+	gracePeriod    time.Duration
+	purgeInterval  time.Duration
+}
+
+func NewAccountService(
+	cfg *config.Config,
+	userService *UserService,
+	tokenService *TokenService,
+	apiKeyService *APIKeyService,
+	auditService *AuditService,
+	statusService *StatusService,
+	authService *AuthService,
+	emailService *EmailService,
+	exportService *ExportService,
+	consentService *ConsentService,
+	handleService *HandleService,
+) *AccountService {
+	gracePeriod := time.Duration(cfg.AccountDeletion.GracePeriodDays) * 24 * time.Hour
+	if gracePeriod <= 0 {
+		gracePeriod = 30 * 24 * time.Hour
+	}
+
+	purgeInterval := time.Duration(cfg.AccountDeletion.PurgeInterval) * time.Second
+	if purgeInterval <= 0 {
+		purgeInterval = time.Hour
+	}
+
+	return &AccountService{
+		userService:    userService,
+		tokenService:   tokenService,
+		apiKeyService:  apiKeyService,
+		auditService:   auditService,
+		statusService:  statusService,
+		authService:    authService,
+		emailService:   emailService,
+		exportService:  exportService,
+		consentService: consentService,
+		handleService:  handleService,
+		gracePeriod:    gracePeriod,
+		purgeInterval:  purgeInterval,
+	}
+}
+
+// RequestDeletion schedules the user's account for purge after re-checking
+// their password, and signs them out everywhere
+func (s *AccountService) RequestDeletion(ctx context.Context, userID primitive.ObjectID, password string) (*models.AccountDeletionScheduled, error) {
+	user, err := s.userService.loadUser(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrNotFound
+	}
+	if err := s.userService.CheckPassword(ctx, user, password); err != nil {
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	from := user.CurrentStatus()
+	now := time.Now()
+	purgeAt := now.Add(s.gracePeriod)
+	if err := s.userService.MarkPendingDeletion(ctx, userID, now, purgeAt); err != nil {
+		if err == ErrVersionConflict {
+			return nil, ErrDeletionAlreadyRequested
+		}
+		return nil, err
+	}
+
+	s.statusService.cutOffAccess(ctx, userID, models.StatusDeleted)
+	s.statusService.RecordChange(ctx, userID, from, models.StatusDeleted, "Deletion requested by user", userID.Hex())
+
+	s.auditService.Record(ctx, models.AuditAccountDeletionRequested, userID, userID.Hex(), map[string]interface{}{
+		"purge_at": purgeAt,
+	})
+
+	return &models.AccountDeletionScheduled{PurgeAt: purgeAt}, nil
+}
+
+// RestoreAccount cancels a pending deletion during the grace period
+func (s *AccountService) RestoreAccount(ctx context.Context, req *models.AccountRestoreRequest) error {
+	user, err := s.userService.AuthenticateUser(ctx, &models.UserLogin{
+		MobileNumber: req.MobileNumber,
+		CountryCode:  req.CountryCode,
+		Email:        req.Email,
+		Password:     req.Password,
+	})
+	if err != nil {
+		return ErrInvalidCredentials
+	}
+	if user.CurrentStatus() != models.StatusDeleted {
+		return ErrNoPendingDeletion
+	}
+
+	// Return the user to the status they had before requesting deletion
+	status := s.statusService.PreviousStatus(ctx, user.ID, models.StatusDeleted, models.StatusActive)
+	if err := s.userService.ClearPendingDeletion(ctx, user.ID, status); err != nil {
+		return err
+	}
+	if err := s.tokenService.UnblockUser(ctx, user.ID.Hex()); err != nil {
+		logger.WithContext(ctx).Error("Failed to unblock restored account", zap.Error(err))
+	}
+	s.statusService.RecordChange(ctx, user.ID, models.StatusDeleted, status, "Account restored by user", user.ID.Hex())
+
+	s.auditService.Record(ctx, models.AuditAccountDeletionCancelled, user.ID, user.ID.Hex(), nil)
+
+	return nil
+}
+
+// PurgeDueAccounts removes every account whose grace period has ended along
+// with its sessions, API keys, data exports and pending reset or verification
+// tokens, status history and handle redirects, anonymizes its consents, and
+// returns how many were purged
+func (s *AccountService) PurgeDueAccounts(ctx context.Context) (int, error) {
+	now := time.Now()
+	users, err := s.userService.GetUsersDueForPurge(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, user := range users {
+		// Claim the user first, so that nothing is deleted from an account
+		// that is being restored or purged by another instance
+		claimed, err := s.userService.ClaimForPurge(ctx, user.ID, now, s.purgeInterval)
+		if err != nil {
+			logger.WithContext(ctx).Error("Failed to claim account for purge", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.tokenService.RevokeUserSessions(ctx, user.ID.Hex()); err != nil {
+			logger.WithContext(ctx).Error("Failed to revoke sessions during purge", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+			continue
+		}
+		if err := s.apiKeyService.DeleteUserKeys(ctx, user.ID); err != nil {
+			logger.WithContext(ctx).Error("Failed to delete API keys during purge", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+			continue
+		}
+
+		if err := s.authService.ForgetPasswordResets(ctx, user.ID.Hex()); err != nil {
+			logger.WithContext(ctx).Error("Failed to drop password reset tokens during purge", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+			continue
+		}
+		if err := s.emailService.ForgetPendingEmails(ctx, user.ID.Hex()); err != nil {
+			logger.WithContext(ctx).Error("Failed to drop pending email verifications during purge", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+			continue
+		}
+		if err := s.exportService.DeleteUserExports(ctx, user.ID); err != nil {
+			logger.WithContext(ctx).Error("Failed to delete data exports during purge", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+			continue
+		}
+		if err := s.consentService.AnonymizeUser(ctx, user.ID); err != nil {
+			logger.WithContext(ctx).Error("Failed to anonymize consents during purge", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+			continue
+		}
+		if err := s.statusService.DeleteHistory(ctx, user.ID); err != nil {
+			logger.WithContext(ctx).Error("Failed to delete status history during purge", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+			continue
+		}
+		if err := s.handleService.DeleteUserRedirects(ctx, user.ID); err != nil {
+			logger.WithContext(ctx).Error("Failed to delete handle redirects during purge", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+			continue
+		}
+
+		deleted, err := s.userService.PurgeUser(ctx, user.ID, now)
+		if err != nil {
+			logger.WithContext(ctx).Error("Failed to purge user", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+			continue
+		}
+		if !deleted {
+			continue
+		}
+		if err := s.tokenService.UnblockUser(ctx, user.ID.Hex()); err != nil {
+			logger.WithContext(ctx).Error("Failed to clear token block during purge", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+		}
+
+		s.auditService.Record(ctx, models.AuditAccountPurged, user.ID, SystemActor, map[string]interface{}{
+			"deletion_requested_at": user.DeletionRequestedAt,
+		})
+		purged++
+	}
+
+	return purged, nil
+}
+
+// RunPurgeLoop purges due accounts on every purge interval until ctx is done
+func (s *AccountService) RunPurgeLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.PurgeDueAccounts(ctx)
+		if err != nil {
+			logger.WithContext(ctx).Error("Account purge failed", zap.Error(err))
+		} else if purged > 0 {
+			logger.WithContext(ctx).Info("Purged deleted accounts", zap.Int("count", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
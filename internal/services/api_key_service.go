@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+)
+
+const (
+	apiKeyPrefix              = "gek_"
+	apiKeyDisplayPrefixLen    = 12
+	defaultAPIKeyLifetimeDays = 90
+)
+
+// ErrInvalidAPIKey is returned for unknown, expired or revoked API keys
+var ErrInvalidAPIKey = errors.New(http.StatusUnauthorized, "Invalid API key")
+
+type APIKeyService struct {
+	collection  *mongo.Collection
+	userService *UserService
+}
+
+func NewAPIKeyService(client *mongo.Client, dbName string, userService *UserService) *APIKeyService {
+	return &APIKeyService{
+		collection:  client.Database(dbName).Collection("api_keys"),
+		userService: userService,
+	}
+}
+
+// CreateKey issues a new API key for the user and returns its secret once
+func (s *APIKeyService) CreateKey(ctx context.Context, userID primitive.ObjectID, req *models.APIKeyCreateRequest) (*models.APIKeyCreated, error) {
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultAPIKeyLifetimeDays
+	}
+
+	secret := apiKeyPrefix + utils.GenerateRandomToken(32)
+	key := &models.APIKey{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    secret[:apiKeyDisplayPrefixLen],
+		KeyHash:   utils.HashToken(secret),
+		Scopes:    req.Scopes,
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := s.collection.InsertOne(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyCreated{
+		APIKey: key,
+		Secret: secret,
+	}, nil
+}
+
+// ListKeys returns the user's API keys, including expired and revoked ones
+func (s *APIKeyService) ListKeys(ctx context.Context, userID primitive.ObjectID) ([]*models.APIKey, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []*models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// RevokeKey permanently disables one of the user's API keys
+func (s *APIKeyService) RevokeKey(ctx context.Context, userID, keyID primitive.ObjectID) error {
+	result, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": keyID, "user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
+// AuthenticateKey resolves a presented API key to its owner and records its use
+func (s *APIKeyService) AuthenticateKey(ctx context.Context, secret string) (*models.APIKey, *models.User, error) {
+	var key models.APIKey
+	err := s.collection.FindOne(ctx, bson.M{"key_hash": utils.HashToken(secret)}).Decode(&key)
+	if err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	if key.RevokedAt != nil || time.Now().After(key.ExpiresAt) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userService.GetUserByID(ctx, key.UserID)
+	if err != nil || !models.StatusAllowsLogin(user.CurrentStatus()) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	key.LastUsedAt = &now
+	_, _ = s.collection.UpdateOne(ctx, bson.M{"_id": key.ID}, bson.M{"$set": bson.M{"last_used_at": now}})
+
+	return &key, user, nil
+}
+
+// DeleteUserKeys removes every API key belonging to a user
+func (s *APIKeyService) DeleteUserKeys(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+)
+
+// SystemActor identifies changes made by background jobs
+const SystemActor = "system"
+
+type AuditService struct {
+	collection *mongo.Collection
+}
+
+func NewAuditService(client *mongo.Client, dbName string) *AuditService {
+	return &AuditService{
+		collection: client.Database(dbName).Collection("audit_events"),
+	}
+}
+
+// Record stores an audit event. Failures are logged rather than returned so
+// that auditing never blocks the action being audited.
+func (s *AuditService) Record(ctx context.Context, action string, userID primitive.ObjectID, actorID string, details map[string]interface{}) {
+	event := &models.AuditEvent{
+		Action:    action,
+		UserID:    userID,
+		ActorID:   actorID,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := s.collection.InsertOne(ctx, event); err != nil {
+		logger.WithContext(ctx).Error("Failed to record audit event",
+			zap.String("action", action),
+			zap.String("user_id", userID.Hex()),
+			zap.Error(err),
+		)
+	}
+}
+
+// ListUserEvents returns the audit trail of a user, newest first
+func (s *AuditService) ListUserEvents(ctx context.Context, userID primitive.ObjectID) ([]*models.AuditEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := s.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []*models.AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -4,50 +4,104 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
 
 	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	apperrors "github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/hashing"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/metrics"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/phone"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/tracing"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
 )
 
+// ErrAccountPendingDeletion blocks login for accounts scheduled for purge
+var ErrAccountPendingDeletion = apperrors.New(
+	http.StatusForbidden,
+	"Account is pending deletion",
+	"Restore the account to log in again",
+)
+
 type AuthService struct {
-	userService *UserService
-	cfg         *config.Config
-	redisClient *redis.Client
-	validate    *validator.Validate
+	userService    *UserService
+	tokenService   *TokenService
+	auditService   *AuditService
+	consentService *ConsentService
+	emailService   *EmailService
+	cfg            *config.Config
+	redisClient    *redis.Client
+	validate       *validator.Validate
 }
 
-const passwordResetKeyFormat = "password_reset:%s"
+const (
+	passwordResetKeyFormat = "password_reset:%s"
+	// passwordResetUserKeyFormat indexes a user's outstanding reset tokens so
+	// that they can be dropped when the account is purged
+	passwordResetUserKeyFormat = "password_reset_user:%s"
+	passwordResetTTL           = 15 * time.Minute
+)
 
-func NewAuthService(userService *UserService, cfg *config.Config, redisClient *redis.Client) *AuthService {
+func NewAuthService(
+	userService *UserService,
+	tokenService *TokenService,
+	auditService *AuditService,
+	consentService *ConsentService,
+	emailService *EmailService,
+	cfg *config.Config,
+	redisClient *redis.Client,
+) *AuthService {
 	return &AuthService{
-		userService: userService,
-		cfg:         cfg,
-		redisClient: redisClient,
-		validate:    validator.New(),
+		userService:    userService,
+		tokenService:   tokenService,
+		auditService:   auditService,
+		consentService: consentService,
+		emailService:   emailService,
+		cfg:            cfg,
+		redisClient:    redisClient,
+		validate:       validator.New(),
 	}
 }
 
 // RegisterUser handles user registration logic
 func (a *AuthService) RegisterUser(ctx context.Context, reg *models.UserRegistration) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RegisterUser")
+	defer span.End()
+
+	// Normalize the number and check it belongs to the given country
+	number, err := phone.Parse(reg.MobileNumber, reg.CountryCode)
+	if err != nil {
+		return nil, apperrors.New(http.StatusBadRequest, "Invalid mobile number", err.Error())
+	}
+
 	// Check if user already exists
-	existingUser, err := a.userService.GetUserByMobileNumber(ctx, reg.MobileNumber)
+	existingUser, err := a.userService.GetUserByMobileNumber(ctx, number.E164, "")
 	if err == nil && existingUser != nil {
 		return nil, errors.New("user already exists")
 	}
 
+	// Registration requires acceptance of the current policies
+	if err := a.consentService.CheckRegistrationAcceptance(ctx, reg); err != nil {
+		return nil, err
+	}
+
 	// Create user object
 	user := &models.User{
-		MobileNumber: reg.MobileNumber,
-		CountryCode:  reg.CountryCode,
+		MobileNumber: number.E164,
+		CountryCode:  number.CountryCode,
+		Region:       number.Region,
+		NumberType:   number.Type,
 		PasswordHash: reg.Password,
 		IsVerified:   false,
+		Status:       models.StatusPending,
 		Roles:        []string{"user"},
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
@@ -58,35 +112,114 @@ func (a *AuthService) RegisterUser(ctx context.Context, reg *models.UserRegistra
 		return nil, err
 	}
 
+	// Record the policy versions accepted at sign-up
+	accepted := map[string]string{
+		models.PolicyTerms:   reg.TermsVersion,
+		models.PolicyPrivacy: reg.PrivacyVersion,
+	}
+	for policyType, version := range accepted {
+		if version == "" {
+			continue
+		}
+		if _, err := a.consentService.RecordConsent(ctx, user.ID, policyType, version, reg.IPAddress, reg.UserAgent); err != nil {
+			a.abortRegistration(ctx, user)
+			return nil, err
+		}
+	}
+
 	// TODO: Send OTP for mobile verification
 
+	metrics.Registrations.Inc()
 	return user, nil
 }
 
-// LoginUser handles user authentication and token generation
-func (a *AuthService) LoginUser(ctx context.Context, login *models.UserLogin) (string, error) {
+// abortRegistration removes a user created by RegisterUser, and any consent
+// already recorded for it, so that the mobile number can register again
+func (a *AuthService) abortRegistration(ctx context.Context, user *models.User) {
+	if err := a.consentService.deleteUserConsents(ctx, user.ID); err != nil {
+		logger.WithContext(ctx).Error("Failed to remove consents of aborted registration", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+	}
+	if err := a.userService.removeUser(ctx, user); err != nil {
+		logger.WithContext(ctx).Error("Failed to remove user of aborted registration", zap.String("user_id", user.ID.Hex()), zap.Error(err))
+	}
+}
+
+// LoginUser handles user authentication and token generation. A non-empty
+// jkt binds the issued token to the client's DPoP key.
+func (a *AuthService) LoginUser(ctx context.Context, login *models.UserLogin, jkt string) (*models.LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.LoginUser")
+	defer span.End()
+
 	user, err := a.userService.AuthenticateUser(ctx, login)
 	if err != nil {
-		return "", err
+		metrics.Logins.WithLabelValues(metrics.LoginFailure, loginFailureReason(err)).Inc()
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	if err := LoginError(user.CurrentStatus()); err != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginFailure, metrics.LoginAccountInactive).Inc()
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	// Look up policies to prompt for before issuing the token, so that a
+	// failed lookup does not leave an unused session behind
+	pending, err := a.consentService.PendingPolicies(ctx, user.ID)
+	if err != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginFailure, metrics.LoginError).Inc()
+		tracing.RecordError(span, err)
+		return nil, err
 	}
 
 	// Generate JWT token
-	token, err := a.generateJWT(user.ID.Hex())
+	token, err := a.tokenService.IssueAccessToken(ctx, user, jkt)
 	if err != nil {
-		return "", err
+		metrics.Logins.WithLabelValues(metrics.LoginFailure, metrics.LoginError).Inc()
+		tracing.RecordError(span, err)
+		return nil, err
 	}
+	metrics.Logins.WithLabelValues(metrics.LoginSuccess, "").Inc()
 
-	// Update last login time
-	user.LastLoginAt = time.Now()
-	a.userService.UpdateUser(ctx, user)
+	// Update last login time, re-reading once if the user changed concurrently
+	loginAt := time.Now()
+	user.LastLoginAt = loginAt
+	if err := a.userService.UpdateUser(ctx, user); errors.Is(err, ErrVersionConflict) {
+		if latest, err := a.userService.loadUser(ctx, user.ID); err == nil {
+			latest.LastLoginAt = loginAt
+			a.userService.UpdateUser(ctx, latest)
+		}
+	}
+
+	a.auditService.Record(ctx, models.AuditUserLogin, user.ID, user.ID.Hex(), map[string]interface{}{
+		"method":     "password",
+		"ip_address": login.IPAddress,
+		"user_agent": login.UserAgent,
+	})
 
-	return token, nil
+	// Prompt for consent again when a new policy version was published
+	result := &models.LoginResult{Token: token}
+	if len(pending) > 0 {
+		result.ConsentRequired = true
+		result.PendingPolicies = pending
+	}
+
+	return result, nil
 }
 
-// PasswordRecovery initiates the password recovery process
+// PasswordRecovery initiates the password recovery process, sending the
+// reset link through the channel the user identified themselves with
 func (a *AuthService) PasswordRecovery(ctx context.Context, req *models.PasswordRecoveryRequest) error {
-	// Find user by mobile number
-	user, err := a.userService.GetUserByMobileNumber(ctx, req.MobileNumber)
+	ctx, span := tracing.Start(ctx, "AuthService.PasswordRecovery")
+	defer span.End()
+
+	// Find user by email or mobile number
+	var user *models.User
+	var err error
+	if req.Email != "" {
+		user, err = a.userService.GetUserByEmail(ctx, req.Email)
+	} else {
+		user, err = a.userService.GetUserByMobileNumber(ctx, req.MobileNumber, req.CountryCode)
+	}
 	if err != nil || user == nil {
 		return errors.New("user not found")
 	}
@@ -94,28 +227,51 @@ func (a *AuthService) PasswordRecovery(ctx context.Context, req *models.Password
 	// Generate a password reset token
 	token := utils.GenerateRandomToken(32)
 
-	// Store token in Redis with expiration (e.g., 15 minutes)
-	err = a.redisClient.Set(ctx, fmt.Sprintf(passwordResetKeyFormat, token), user.ID.Hex(), 15*time.Minute).Err()
+	// Store token in Redis with expiration, indexed under the user
+	indexKey := fmt.Sprintf(passwordResetUserKeyFormat, user.ID.Hex())
+	pipe := a.redisClient.TxPipeline()
+	pipe.Set(ctx, fmt.Sprintf(passwordResetKeyFormat, token), user.ID.Hex(), passwordResetTTL)
+	pipe.SAdd(ctx, indexKey, token)
+	pipe.Expire(ctx, indexKey, passwordResetTTL)
+	_, err = pipe.Exec(ctx)
+	if err == redisguard.ErrUnavailable {
+		return err
+	}
 	if err != nil {
 		return errors.New("failed to generate password reset token")
 	}
 
-	resetLink := fmt.Sprintf("https://yourdomain.com/reset-password?token=%s", token)
+	resetLink := a.emailService.webAppLink("/reset-password", token)
+	metrics.PasswordResets.WithLabelValues("requested").Inc()
+	if req.Email != "" {
+		if err := a.emailService.SendPasswordReset(user.Email, resetLink); err != nil {
+			return err
+		}
+		metrics.OTPsSent.WithLabelValues("password_reset", "email").Inc()
+		return nil
+	}
+
 	smsMessage := fmt.Sprintf("Your password reset token is: %s", resetLink)
 
 	if err := utils.SendSMS(user.MobileNumber, smsMessage); err != nil {
 		return errors.New("failed to send SMS")
 	}
+	metrics.OTPsSent.WithLabelValues("password_reset", "sms").Inc()
 
 	return nil
 }
 
 // ResetPassword completes the password reset process
 func (a *AuthService) ResetPassword(ctx context.Context, req *models.ResetPasswordRequest) error {
+	ctx, span := tracing.Start(ctx, "AuthService.ResetPassword")
+	defer span.End()
+
 	// Retrieve user ID from Redis using the token
 	userID, err := a.redisClient.Get(ctx, fmt.Sprintf(passwordResetKeyFormat, req.Token)).Result()
 	if err == redis.Nil {
 		return errors.New("invalid or expired token")
+	} else if err == redisguard.ErrUnavailable {
+		return err
 	} else if err != nil {
 		return errors.New("failed to validate token")
 	}
@@ -125,43 +281,79 @@ func (a *AuthService) ResetPassword(ctx context.Context, req *models.ResetPasswo
 	if err != nil {
 		return errors.New("invalid user ID")
 	}
-	user, err := a.userService.GetUserByID(ctx, userObjectID)
+	user, err := a.userService.loadUser(ctx, userObjectID)
 	if err != nil {
 		return errors.New("user not found")
 	}
 
 	// Update password
-	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	hashedPassword, err := a.userService.HashPassword(ctx, req.NewPassword)
+	if err == hashing.ErrBusy {
+		return err
+	}
 	if err != nil {
 		return errors.New("failed to hash password")
 	}
 	user.PasswordHash = hashedPassword
 	user.UpdatedAt = time.Now()
 
-	if err := a.userService.UpdateUser(ctx, user); err != nil {
+	// Retry once on the latest version if the user changed since it was
+	// read, e.g. by a login
+	err = a.userService.UpdateUser(ctx, user)
+	if errors.Is(err, ErrVersionConflict) {
+		if latest, loadErr := a.userService.loadUser(ctx, user.ID); loadErr == nil {
+			latest.PasswordHash = hashedPassword
+			latest.UpdatedAt = user.UpdatedAt
+			err = a.userService.UpdateUser(ctx, latest)
+		}
+	}
+	if errors.Is(err, ErrVersionConflict) {
+		return ErrVersionConflict
+	}
+	if err != nil {
 		return errors.New("failed to update password")
 	}
 
 	// Delete the reset token
 	a.redisClient.Del(ctx, fmt.Sprintf(passwordResetKeyFormat, req.Token))
+	a.redisClient.SRem(ctx, fmt.Sprintf(passwordResetUserKeyFormat, userID), req.Token)
 
+	metrics.PasswordResets.WithLabelValues("completed").Inc()
 	return nil
 }
 
-func (a *AuthService) generateJWT(userID string) (string, error) {
-	// Define token claims
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(), // Token expires in 72 hours
+// ForgetPasswordResets drops every outstanding password reset token of a user
+func (a *AuthService) ForgetPasswordResets(ctx context.Context, userID string) error {
+	indexKey := fmt.Sprintf(passwordResetUserKeyFormat, userID)
+	tokens, err := a.redisClient.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return err
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	keys := make([]string, 0, len(tokens)+1)
+	for _, token := range tokens {
+		keys = append(keys, fmt.Sprintf(passwordResetKeyFormat, token))
+	}
+	keys = append(keys, indexKey)
+	return a.redisClient.Del(ctx, keys...).Err()
+}
 
-	// Sign token with secret
-	return token.SignedString([]byte(a.cfg.JWT.Secret))
+// loginFailureReason classifies a failed password check for metrics
+func loginFailureReason(err error) string {
+	switch err {
+	case errUserNotFound:
+		return metrics.LoginUnknownUser
+	case errInvalidCredentials:
+		return metrics.LoginWrongPassword
+	case hashing.ErrBusy:
+		return metrics.LoginBusy
+	}
+	return metrics.LoginError
 }
 
 func (a *AuthService) GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GetUserByID")
+	defer span.End()
+
 	return a.userService.GetUserByID(ctx, id)
 }
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/hashing"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+)
+
+var (
+	// ErrInvalidClient is returned when client credentials cannot be verified
+	ErrInvalidClient = errors.New(http.StatusUnauthorized, "Invalid client credentials")
+	// ErrInvalidScope is returned when a client asks for scopes it was not granted
+	ErrInvalidScope = errors.New(http.StatusBadRequest, "Requested scope is not allowed for this client")
+)
+
+type ClientService struct {
+	collection *mongo.Collection
+	hasher     *hashing.Pool
+}
+
+func NewClientService(client *mongo.Client, dbName string, hasher *hashing.Pool) *ClientService {
+	return &ClientService{
+		collection: client.Database(dbName).Collection("service_clients"),
+		hasher:     hasher,
+	}
+}
+
+// CreateClient registers a new service client and returns its plaintext
+// secret. Only the bcrypt hash of the secret is stored.
+func (s *ClientService) CreateClient(ctx context.Context, reg *models.ClientRegistration) (*models.ClientCredentials, error) {
+	secret := utils.GenerateRandomToken(32)
+	secretHash, err := s.hasher.Hash(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &models.ServiceClient{
+		ClientID:   "svc_" + utils.GenerateRandomToken(12),
+		Name:       reg.Name,
+		SecretHash: secretHash,
+		Scopes:     reg.Scopes,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	if _, err := s.collection.InsertOne(ctx, client); err != nil {
+		return nil, err
+	}
+
+	return &models.ClientCredentials{
+		Client:       client,
+		ClientSecret: secret,
+	}, nil
+}
+
+// ListClients returns every registered service client
+func (s *ClientService) ListClients(ctx context.Context) ([]*models.ServiceClient, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	clients := []*models.ServiceClient{}
+	if err := cursor.All(ctx, &clients); err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+// DisableClient stops a client from authenticating. Tokens already issued to
+// it stay valid until they expire.
+func (s *ClientService) DisableClient(ctx context.Context, clientID string) error {
+	result, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"client_id": clientID},
+		bson.M{
+			"$set": bson.M{
+				"disabled":   true,
+				"updated_at": time.Now(),
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
+// AuthenticateClient verifies a client ID and secret against the registry
+func (s *ClientService) AuthenticateClient(ctx context.Context, clientID, secret string) (*models.ServiceClient, error) {
+	if clientID == "" || secret == "" {
+		return nil, ErrInvalidClient
+	}
+
+	var client models.ServiceClient
+	err := s.collection.FindOne(ctx, bson.M{"client_id": clientID}).Decode(&client)
+	if err != nil {
+		return nil, ErrInvalidClient
+	}
+
+	if client.Disabled {
+		return nil, ErrInvalidClient
+	}
+
+	if err := s.hasher.Compare(ctx, secret, client.SecretHash); err != nil {
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// ResolveScopes narrows a space-delimited scope request to the scopes granted
+// to the client. An empty request yields every granted scope.
+func (s *ClientService) ResolveScopes(client *models.ServiceClient, requested string) ([]string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return client.Scopes, nil
+	}
+
+	scopes := strings.Fields(requested)
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	return scopes, nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+)
+
+var (
+	// ErrPolicyVersionExists is returned when publishing a version twice
+	ErrPolicyVersionExists = errors.New(http.StatusConflict, "Policy version already exists")
+	// ErrPolicyNotFound is returned when consenting to an unknown policy version
+	ErrPolicyNotFound = errors.New(http.StatusNotFound, "Policy version not found")
+)
+
+// ConsentService keeps the registry of published policy documents and the
+// record of which versions each user accepted
+type ConsentService struct {
+	policies    *mongo.Collection
+	consents    *mongo.Collection
+	userService *UserService
+}
+
+func NewConsentService(client *mongo.Client, dbName string, userService *UserService) *ConsentService {
+	db := client.Database(dbName)
+	return &ConsentService{
+		policies:    db.Collection("policy_documents"),
+		consents:    db.Collection("consents"),
+		userService: userService,
+	}
+}
+
+// EnsureIndexes makes each version of a policy type unique
+func (s *ConsentService) EnsureIndexes(ctx context.Context) error {
+	_, err := s.policies.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "type", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// PublishPolicy registers a new policy version. It becomes current at its
+// publication time, which defaults to now.
+func (s *ConsentService) PublishPolicy(ctx context.Context, req *models.PolicyPublishRequest) (*models.PolicyDocument, error) {
+	publishedAt := time.Now()
+	if req.PublishedAt != nil {
+		publishedAt = *req.PublishedAt
+	}
+
+	policy := &models.PolicyDocument{
+		ID:          primitive.NewObjectID(),
+		Type:        req.Type,
+		Version:     req.Version,
+		URL:         req.URL,
+		PublishedAt: publishedAt,
+		CreatedAt:   time.Now(),
+	}
+	_, err := s.policies.InsertOne(ctx, policy)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrPolicyVersionExists
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// ListPolicies returns every registered policy version, newest first
+func (s *ConsentService) ListPolicies(ctx context.Context) ([]*models.PolicyDocument, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "published_at", Value: -1}})
+	cursor, err := s.policies.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	policies := []*models.PolicyDocument{}
+	if err := cursor.All(ctx, &policies); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// CurrentPolicies returns the latest published version of each policy type.
+// Types that have never been published are omitted.
+func (s *ConsentService) CurrentPolicies(ctx context.Context) ([]*models.PolicyDocument, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "published_at", Value: -1}})
+
+	var current []*models.PolicyDocument
+	for _, policyType := range models.PolicyTypes {
+		var policy models.PolicyDocument
+		err := s.policies.FindOne(ctx, bson.M{
+			"type":         policyType,
+			"published_at": bson.M{"$lte": time.Now()},
+		}, opts).Decode(&policy)
+		if err == mongo.ErrNoDocuments {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		current = append(current, &policy)
+	}
+
+	return current, nil
+}
+
+// CheckRegistrationAcceptance verifies that a registration accepted the
+// current version of every published policy
+func (s *ConsentService) CheckRegistrationAcceptance(ctx context.Context, reg *models.UserRegistration) error {
+	current, err := s.CurrentPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	accepted := map[string]string{
+		models.PolicyTerms:   reg.TermsVersion,
+		models.PolicyPrivacy: reg.PrivacyVersion,
+	}
+
+	var details []string
+	for _, policy := range current {
+		if accepted[policy.Type] != policy.Version {
+			details = append(details, fmt.Sprintf("%s: version %s must be accepted", policy.Type, policy.Version))
+		}
+	}
+	if len(details) > 0 {
+		return errors.New(http.StatusBadRequest, "Policy acceptance required", details...)
+	}
+
+	return nil
+}
+
+// RecordConsent stores a user's acceptance of a policy version
+func (s *ConsentService) RecordConsent(ctx context.Context, userID primitive.ObjectID, policyType, version, ipAddress, userAgent string) (*models.Consent, error) {
+	count, err := s.policies.CountDocuments(ctx, bson.M{"type": policyType, "version": version})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrPolicyNotFound
+	}
+
+	consent := &models.Consent{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		PolicyType: policyType,
+		Version:    version,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		AcceptedAt: time.Now(),
+	}
+	if _, err := s.consents.InsertOne(ctx, consent); err != nil {
+		return nil, err
+	}
+
+	return consent, nil
+}
+
+// deleteUserConsents removes the consents of a user whose registration
+// could not be completed
+func (s *ConsentService) deleteUserConsents(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.consents.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// PendingPolicies returns the current policies the user has not yet accepted
+func (s *ConsentService) PendingPolicies(ctx context.Context, userID primitive.ObjectID) ([]*models.PolicyDocument, error) {
+	current, err := s.CurrentPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []*models.PolicyDocument{}
+	for _, policy := range current {
+		count, err := s.consents.CountDocuments(ctx, bson.M{
+			"user_id":     userID,
+			"policy_type": policy.Type,
+			"version":     policy.Version,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			pending = append(pending, policy)
+		}
+	}
+
+	return pending, nil
+}
+
+// ListUserConsents returns every consent the user has given, newest first
+func (s *ConsentService) ListUserConsents(ctx context.Context, userID primitive.ObjectID) ([]*models.Consent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "accepted_at", Value: -1}})
+	cursor, err := s.consents.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	consents := []*models.Consent{}
+	if err := cursor.All(ctx, &consents); err != nil {
+		return nil, err
+	}
+
+	return consents, nil
+}
+
+// AnonymizeUser strips the user's identity, IP address and user agent from
+// their consents while keeping which versions were accepted and when
+func (s *ConsentService) AnonymizeUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.consents.UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{"$set": bson.M{
+		"user_id":       primitive.NilObjectID,
+		"ip_address":    "",
+		"user_agent":    "",
+		"anonymized_at": time.Now(),
+	}})
+	return err
+}
+
+// Coverage reports, for the current version of each policy, how many users
+// have accepted it
+func (s *ConsentService) Coverage(ctx context.Context) ([]*models.ConsentCoverage, error) {
+	current, err := s.CurrentPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.userService.CountUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	coverage := []*models.ConsentCoverage{}
+	for _, policy := range current {
+		// Consents of purged users no longer count towards any user
+		userIDs, err := s.consents.Distinct(ctx, "user_id", bson.M{
+			"policy_type":   policy.Type,
+			"version":       policy.Version,
+			"anonymized_at": bson.M{"$exists": false},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &models.ConsentCoverage{
+			PolicyType:    policy.Type,
+			Version:       policy.Version,
+			AcceptedUsers: int64(len(userIDs)),
+			TotalUsers:    total,
+		}
+		if total > 0 {
+			entry.Coverage = float64(entry.AcceptedUsers) / float64(total)
+		}
+		coverage = append(coverage, entry)
+	}
+
+	return coverage, nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
+)
+
+const dpopProofKeyFormat = "dpop_jti:%s:%s"
+
+// ErrInvalidDPoPProof is returned when a DPoP proof fails any RFC 9449 check
+var ErrInvalidDPoPProof = errors.New(http.StatusUnauthorized, "Invalid DPoP proof")
+
+// dpopSigningMethods are the asymmetric algorithms accepted for DPoP proofs
+var dpopSigningMethods = []string{"ES256", "ES384", "RS256", "PS256", "EdDSA"}
+
+type dpopClaims struct {
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	ATH string `json:"ath,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// jsonWebKey holds the public members of a JWK used in a DPoP proof header
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"`
+}
+
+// DPoPService verifies DPoP proofs (RFC 9449) and guards against proof replay
+type DPoPService struct {
+	redisClient *redis.Client
+	redisGuard  *redisguard.Guard
+	maxAge      time.Duration
+	publicURL   string
+}
+
+func NewDPoPService(cfg *config.Config, redisGuard *redisguard.Guard) *DPoPService {
+	maxAge := time.Duration(cfg.DPoP.ProofMaxAge) * time.Second
+	if maxAge <= 0 {
+		maxAge = 5 * time.Minute
+	}
+
+	return &DPoPService{
+		redisClient: redisGuard.Client(),
+		redisGuard:  redisGuard,
+		maxAge:      maxAge,
+		publicURL:   strings.TrimRight(cfg.Server.PublicURL, "/"),
+	}
+}
+
+// RequestURL returns the URL a client is expected to put in the htu claim
+// for the given request
+func (s *DPoPService) RequestURL(r *http.Request) string {
+	if s.publicURL != "" {
+		return s.publicURL + r.URL.Path
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
+
+// VerifyProof validates a DPoP proof for the given request and returns the
+// JWK thumbprint of the key that signed it. When accessToken is set the proof
+// must also carry a matching ath claim.
+func (s *DPoPService) VerifyProof(ctx context.Context, proof, method, requestURL, accessToken string) (string, error) {
+	if proof == "" {
+		return "", ErrInvalidDPoPProof
+	}
+
+	var thumbprint string
+	claims := &dpopClaims{}
+	token, err := jwt.ParseWithClaims(proof, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != "dpop+jwt" {
+			return nil, ErrInvalidDPoPProof
+		}
+
+		key, jkt, err := parseProofKey(token.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+		thumbprint = jkt
+		return key, nil
+	}, jwt.WithValidMethods(dpopSigningMethods))
+	if err != nil || !token.Valid {
+		return "", ErrInvalidDPoPProof
+	}
+
+	// Proofs must be fresh and bound to this exact request
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return "", ErrInvalidDPoPProof
+	}
+	age := time.Since(claims.IssuedAt.Time)
+	if age > s.maxAge || age < -s.maxAge {
+		return "", ErrInvalidDPoPProof
+	}
+	if claims.HTM != method || !sameURL(claims.HTU, requestURL) {
+		return "", ErrInvalidDPoPProof
+	}
+
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		if claims.ATH != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			return "", ErrInvalidDPoPProof
+		}
+	}
+
+	// Each proof may only be used once while it is fresh. Without Redis,
+	// proofs are only accepted if the policy allows skipping this check.
+	stored, err := s.redisClient.SetNX(ctx, fmt.Sprintf(dpopProofKeyFormat, thumbprint, claims.ID), "1", 2*s.maxAge).Result()
+	if err == redisguard.ErrUnavailable {
+		if err := s.redisGuard.Degrade(redisguard.FeatureDPoPReplay, err); err != nil {
+			return "", err
+		}
+		stored = true
+	} else if err != nil {
+		return "", ErrInvalidDPoPProof
+	}
+	if !stored {
+		return "", ErrInvalidDPoPProof
+	}
+
+	return thumbprint, nil
+}
+
+// parseProofKey decodes the public JWK from a proof header and computes its
+// RFC 7638 thumbprint
+func parseProofKey(raw interface{}) (interface{}, string, error) {
+	encoded, err := json.Marshal(raw)
+	if err != nil || raw == nil {
+		return nil, "", ErrInvalidDPoPProof
+	}
+
+	var jwk jsonWebKey
+	if err := json.Unmarshal(encoded, &jwk); err != nil {
+		return nil, "", ErrInvalidDPoPProof
+	}
+
+	// A proof must never carry private key material
+	if jwk.D != "" {
+		return nil, "", ErrInvalidDPoPProof
+	}
+
+	var key interface{}
+	var members string
+	switch jwk.Kty {
+	case "EC":
+		key, err = ecPublicKey(jwk)
+		members = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, jwk.Crv, jwk.X, jwk.Y)
+	case "RSA":
+		key, err = rsaPublicKey(jwk)
+		members = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	case "OKP":
+		key, err = ed25519PublicKey(jwk)
+		members = fmt.Sprintf(`{"crv":"%s","kty":"OKP","x":"%s"}`, jwk.Crv, jwk.X)
+	default:
+		err = ErrInvalidDPoPProof
+	}
+	if err != nil {
+		return nil, "", ErrInvalidDPoPProof
+	}
+
+	sum := sha256.Sum256([]byte(members))
+	return key, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func ecPublicKey(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	var ecdhCurve ecdh.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+	default:
+		return nil, ErrInvalidDPoPProof
+	}
+
+	x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+	y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+	size := (curve.Params().BitSize + 7) / 8
+	if errX != nil || errY != nil || len(x) != size || len(y) != size {
+		return nil, ErrInvalidDPoPProof
+	}
+
+	// Reject points that are not on the curve
+	point := append([]byte{4}, append(x, y...)...)
+	if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+		return nil, ErrInvalidDPoPProof
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
+
+func rsaPublicKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+	e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+	if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+		return nil, ErrInvalidDPoPProof
+	}
+
+	key := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}
+	if key.N.BitLen() < 2048 || key.E < 3 {
+		return nil, ErrInvalidDPoPProof
+	}
+
+	return key, nil
+}
+
+func ed25519PublicKey(jwk jsonWebKey) (ed25519.PublicKey, error) {
+	if jwk.Crv != "Ed25519" {
+		return nil, ErrInvalidDPoPProof
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, ErrInvalidDPoPProof
+	}
+
+	return ed25519.PublicKey(x), nil
+}
+
+// sameURL compares an htu claim with the request URL, ignoring query and
+// fragment as required by RFC 9449 section 4.3
+func sameURL(htu, requestURL string) bool {
+	a, errA := url.Parse(htu)
+	b, errB := url.Parse(requestURL)
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Host, b.Host) &&
+		a.Path == b.Path
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
+)
+
+const (
+	testTokenURL    = "https://api.example/api/v1/oauth/token"
+	testAccessToken = "access-token"
+)
+
+// newTestDPoPService returns a DPoP service whose replay check runs against
+// an in-memory Redis
+func newTestDPoPService(t *testing.T) *DPoPService {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	guard, err := redisguard.New(client, nil, time.Minute)
+	if err != nil {
+		t.Fatalf("redisguard.New: %v", err)
+	}
+	if err := guard.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	var cfg config.Config
+	cfg.DPoP.ProofMaxAge = 60
+	return NewDPoPService(&cfg, guard)
+}
+
+// proofKey is an EC key that signs test proofs
+type proofKey struct {
+	private *ecdsa.PrivateKey
+	jwk     map[string]interface{}
+}
+
+func newProofKey(t *testing.T) *proofKey {
+	t.Helper()
+
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	private.PublicKey.X.FillBytes(x)
+	private.PublicKey.Y.FillBytes(y)
+
+	return &proofKey{
+		private: private,
+		jwk: map[string]interface{}{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   encode(x),
+			"y":   encode(y),
+		},
+	}
+}
+
+// sign builds a proof from claims, letting header override the defaults
+func (k *proofKey) sign(t *testing.T, claims *dpopClaims, header map[string]interface{}) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = "dpop+jwt"
+	token.Header["jwk"] = k.jwk
+	for name, value := range header {
+		token.Header[name] = value
+	}
+
+	proof, err := token.SignedString(k.private)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return proof
+}
+
+func proofClaims(jti, method, htu string) *dpopClaims {
+	return &dpopClaims{
+		HTM: method,
+		HTU: htu,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       jti,
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+}
+
+func accessTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestVerifyProof(t *testing.T) {
+	key := newProofKey(t)
+
+	withPrivateKey := map[string]interface{}{}
+	for name, value := range key.jwk {
+		withPrivateKey[name] = value
+	}
+	withPrivateKey["d"] = base64.RawURLEncoding.EncodeToString(key.private.D.Bytes())
+
+	bound := proofClaims("bound", "GET", testTokenURL)
+	bound.ATH = accessTokenHash(testAccessToken)
+	wrongATH := proofClaims("wrong-ath", "GET", testTokenURL)
+	wrongATH.ATH = accessTokenHash("another-token")
+	stale := proofClaims("stale", "POST", testTokenURL)
+	stale.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+
+	cases := []struct {
+		name        string
+		proof       string
+		method      string
+		accessToken string
+		valid       bool
+	}{
+		{
+			name:   "valid",
+			proof:  key.sign(t, proofClaims("valid", "POST", testTokenURL), nil),
+			method: "POST",
+			valid:  true,
+		},
+		{
+			name:   "query and fragment ignored",
+			proof:  key.sign(t, proofClaims("query", "POST", testTokenURL+"?a=1#b"), nil),
+			method: "POST",
+			valid:  true,
+		},
+		{
+			name:   "wrong htm",
+			proof:  key.sign(t, proofClaims("htm", "GET", testTokenURL), nil),
+			method: "POST",
+		},
+		{
+			name:   "wrong htu",
+			proof:  key.sign(t, proofClaims("htu", "POST", "https://api.example/api/v1/oauth/revoke"), nil),
+			method: "POST",
+		},
+		{
+			name:   "htu on another host",
+			proof:  key.sign(t, proofClaims("host", "POST", "https://evil.example/api/v1/oauth/token"), nil),
+			method: "POST",
+		},
+		{
+			name:        "matching ath",
+			proof:       key.sign(t, bound, nil),
+			method:      "GET",
+			accessToken: testAccessToken,
+			valid:       true,
+		},
+		{
+			name:        "missing ath",
+			proof:       key.sign(t, proofClaims("no-ath", "GET", testTokenURL), nil),
+			method:      "GET",
+			accessToken: testAccessToken,
+		},
+		{
+			name:        "wrong ath",
+			proof:       key.sign(t, wrongATH, nil),
+			method:      "GET",
+			accessToken: testAccessToken,
+		},
+		{
+			name:   "private key in jwk",
+			proof:  key.sign(t, proofClaims("private", "POST", testTokenURL), map[string]interface{}{"jwk": withPrivateKey}),
+			method: "POST",
+		},
+		{
+			name:   "wrong typ",
+			proof:  key.sign(t, proofClaims("typ", "POST", testTokenURL), map[string]interface{}{"typ": "JWT"}),
+			method: "POST",
+		},
+		{
+			name:   "missing jti",
+			proof:  key.sign(t, proofClaims("", "POST", testTokenURL), nil),
+			method: "POST",
+		},
+		{
+			name:   "stale iat",
+			proof:  key.sign(t, stale, nil),
+			method: "POST",
+		},
+		{
+			name:   "signed by another key",
+			proof:  newProofKey(t).sign(t, proofClaims("other-key", "POST", testTokenURL), map[string]interface{}{"jwk": key.jwk}),
+			method: "POST",
+		},
+	}
+
+	s := newTestDPoPService(t)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			jkt, err := s.VerifyProof(context.Background(), tc.proof, tc.method, testTokenURL, tc.accessToken)
+			if tc.valid {
+				if err != nil {
+					t.Fatalf("VerifyProof: %v", err)
+				}
+				if jkt == "" {
+					t.Fatal("VerifyProof returned no thumbprint")
+				}
+				return
+			}
+			if err != ErrInvalidDPoPProof {
+				t.Fatalf("VerifyProof error = %v, want %v", err, ErrInvalidDPoPProof)
+			}
+		})
+	}
+}
+
+func TestVerifyProofReplay(t *testing.T) {
+	s := newTestDPoPService(t)
+	key := newProofKey(t)
+	ctx := context.Background()
+
+	proof := key.sign(t, proofClaims("replayed", "POST", testTokenURL), nil)
+	first, err := s.VerifyProof(ctx, proof, "POST", testTokenURL, "")
+	if err != nil {
+		t.Fatalf("first VerifyProof: %v", err)
+	}
+	if _, err := s.VerifyProof(ctx, proof, "POST", testTokenURL, ""); err != ErrInvalidDPoPProof {
+		t.Fatalf("replayed VerifyProof error = %v, want %v", err, ErrInvalidDPoPProof)
+	}
+
+	// The same jti is only a replay for the key that used it
+	other := newProofKey(t).sign(t, proofClaims("replayed", "POST", testTokenURL), nil)
+	second, err := s.VerifyProof(ctx, other, "POST", testTokenURL, "")
+	if err != nil {
+		t.Fatalf("VerifyProof with another key: %v", err)
+	}
+	if first == second {
+		t.Errorf("both keys have thumbprint %s", first)
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/mailer"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/metrics"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+)
+
+const (
+	emailVerificationKeyFormat = "email_verification:%s"
+	// emailVerificationUserKeyFormat indexes a user's pending verifications
+	// so that they can be dropped when the account is purged
+	emailVerificationUserKeyFormat = "email_verification_user:%s"
+)
+
+var (
+	// ErrInvalidVerificationToken is returned for unknown or expired tokens
+	ErrInvalidVerificationToken = errors.New(http.StatusBadRequest, "Invalid or expired verification token")
+	// ErrEmailDelivery is returned when the SMTP server rejects a message
+	ErrEmailDelivery = errors.New(http.StatusServiceUnavailable, "Failed to send email")
+)
+
+// pendingEmail is the Redis payload of an unconfirmed email address
+type pendingEmail struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// EmailService lets users add an email address as a secondary identity. An
+// address is only stored on the user, and counted for uniqueness, once the
+// user follows the link sent to it.
+type EmailService struct {
+	userService  *UserService
+	auditService *AuditService
+	mailer       *mailer.Mailer
+	redisClient  *redis.Client
+	tokenTTL     time.Duration
+	webAppURL    string
+}
+
+func NewEmailService(cfg *config.Config, redisClient *redis.Client, mailer *mailer.Mailer, userService *UserService, auditService *AuditService) (*EmailService, error) {
+	// Links in messages are opened outside the app, so they must be absolute
+	webAppURL, err := url.Parse(cfg.Server.WebAppURL)
+	if err != nil || webAppURL.Scheme == "" || webAppURL.Host == "" {
+		return nil, fmt.Errorf("server.web_app_url must be an absolute URL, got %q", cfg.Server.WebAppURL)
+	}
+
+	tokenTTL := time.Duration(cfg.EmailVerification.TokenTTL) * time.Minute
+	if tokenTTL <= 0 {
+		tokenTTL = 24 * time.Hour
+	}
+
+	return &EmailService{
+		userService:  userService,
+		auditService: auditService,
+		mailer:       mailer,
+		redisClient:  redisClient,
+		tokenTTL:     tokenTTL,
+		webAppURL:    strings.TrimRight(webAppURL.String(), "/"),
+	}, nil
+}
+
+// RequestVerification emails a verification link for a new address. The
+// user's current address, if any, stays in place until the link is used.
+func (s *EmailService) RequestVerification(ctx context.Context, userID primitive.ObjectID, email string) error {
+	email = NormalizeEmail(email)
+	if owner, err := s.userService.GetUserByEmail(ctx, email); err == nil && owner.ID != userID {
+		return ErrEmailTaken
+	}
+
+	payload, err := json.Marshal(pendingEmail{UserID: userID.Hex(), Email: email})
+	if err != nil {
+		return err
+	}
+
+	token := utils.GenerateRandomToken(32)
+	tokenHash := utils.HashToken(token)
+	key := fmt.Sprintf(emailVerificationKeyFormat, tokenHash)
+	indexKey := fmt.Sprintf(emailVerificationUserKeyFormat, userID.Hex())
+	pipe := s.redisClient.TxPipeline()
+	pipe.Set(ctx, key, payload, s.tokenTTL)
+	pipe.SAdd(ctx, indexKey, tokenHash)
+	pipe.Expire(ctx, indexKey, s.tokenTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	link := s.webAppLink("/verify-email", token)
+	body := fmt.Sprintf(
+		"Confirm this email address for your GreenEye account by opening the link below:\n\n%s\n\nThe link expires in %s. If you did not request this, you can ignore this email.\n",
+		link,
+		s.tokenTTL,
+	)
+	if err := s.mailer.Send(email, "Verify your email address", body); err != nil {
+		s.redisClient.Del(ctx, key)
+		s.redisClient.SRem(ctx, indexKey, tokenHash)
+		return ErrEmailDelivery
+	}
+	metrics.OTPsSent.WithLabelValues("email_verification", "email").Inc()
+
+	return nil
+}
+
+// Verify confirms the address a verification token was sent to and stores it
+// on the user
+func (s *EmailService) Verify(ctx context.Context, token string) (*models.User, error) {
+	tokenHash := utils.HashToken(token)
+	payload, err := s.redisClient.GetDel(ctx, fmt.Sprintf(emailVerificationKeyFormat, tokenHash)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pending pendingEmail
+	if err := json.Unmarshal(payload, &pending); err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+	userID, err := primitive.ObjectIDFromHex(pending.UserID)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+	s.redisClient.SRem(ctx, fmt.Sprintf(emailVerificationUserKeyFormat, pending.UserID), tokenHash)
+
+	if err := s.userService.SetVerifiedEmail(ctx, userID, pending.Email); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(ctx, models.AuditEmailVerified, userID, userID.Hex(), nil)
+
+	return s.userService.GetUserByID(ctx, userID)
+}
+
+// ForgetPendingEmails drops every unconfirmed address a user asked to verify
+func (s *EmailService) ForgetPendingEmails(ctx context.Context, userID string) error {
+	indexKey := fmt.Sprintf(emailVerificationUserKeyFormat, userID)
+	tokenHashes, err := s.redisClient.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(tokenHashes)+1)
+	for _, tokenHash := range tokenHashes {
+		keys = append(keys, fmt.Sprintf(emailVerificationKeyFormat, tokenHash))
+	}
+	keys = append(keys, indexKey)
+	return s.redisClient.Del(ctx, keys...).Err()
+}
+
+// RemoveEmail removes the user's email address
+func (s *EmailService) RemoveEmail(ctx context.Context, userID primitive.ObjectID) error {
+	if err := s.userService.RemoveEmail(ctx, userID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, models.AuditEmailRemoved, userID, userID.Hex(), nil)
+	return nil
+}
+
+// webAppLink returns the web app page at path carrying token
+func (s *EmailService) webAppLink(path, token string) string {
+	return s.webAppURL + path + "?" + url.Values{"token": {token}}.Encode()
+}
+
+// SendPasswordReset emails a password reset link to a verified address
+func (s *EmailService) SendPasswordReset(email, resetLink string) error {
+	body := fmt.Sprintf(
+		"Use the link below to reset your GreenEye password:\n\n%s\n\nIf you did not request a password reset, you can ignore this email.\n",
+		resetLink,
+	)
+	if err := s.mailer.Send(email, "Reset your password", body); err != nil {
+		return ErrEmailDelivery
+	}
+	return nil
+}
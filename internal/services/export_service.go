@@ -0,0 +1,445 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+)
+
+var (
+	// ErrExportNotFound is returned for unknown exports or exports owned by someone else
+	ErrExportNotFound = errors.New(http.StatusNotFound, "Export not found")
+	// ErrExportNotReady is returned when downloading an export that has not completed
+	ErrExportNotReady = errors.New(http.StatusConflict, "Export is not ready for download")
+	// ErrInvalidDownloadLink is returned for expired or tampered download links
+	ErrInvalidDownloadLink = errors.New(http.StatusForbidden, "Download link is invalid or has expired")
+)
+
+// minSigningKeyLength is the shortest data_export.signing_key accepted, in bytes
+const minSigningKeyLength = 32
+
+// exportSection is one JSON file in a data export archive
+type exportSection struct {
+	name    string
+	collect func(ctx context.Context, user *models.User) (interface{}, error)
+}
+
+// ExportService builds personal data exports in the background. Each export
+// is a ZIP of JSON files with a manifest signed by the configured key, and is
+// downloaded through short-lived signed links.
+type ExportService struct {
+	collection     *mongo.Collection
+	userService    *UserService
+	tokenService   *TokenService
+	apiKeyService  *APIKeyService
+	auditService   *AuditService
+	consentService *ConsentService
+	directory      string
+	signingKey     []byte
+	linkTTL        time.Duration
+	retention      time.Duration
+	pollInterval   time.Duration
+	publicURL      string
+	sections       []exportSection
+}
+
+func NewExportService(
+	client *mongo.Client,
+	dbName string,
+	cfg *config.Config,
+	userService *UserService,
+	tokenService *TokenService,
+	apiKeyService *APIKeyService,
+	auditService *AuditService,
+	consentService *ConsentService,
+) (*ExportService, error) {
+	// An unset environment variable leaves the placeholder in place, which
+	// would otherwise become a well-known key
+	signingKey := cfg.DataExport.SigningKey
+	switch {
+	case signingKey == "":
+		return nil, fmt.Errorf("data_export.signing_key is not set")
+	case strings.Contains(signingKey, "${"):
+		return nil, fmt.Errorf("data_export.signing_key refers to an unset environment variable")
+	case len(signingKey) < minSigningKeyLength:
+		return nil, fmt.Errorf("data_export.signing_key must be at least %d bytes", minSigningKeyLength)
+	}
+
+	directory := cfg.DataExport.Directory
+	if directory == "" {
+		directory = "./exports"
+	}
+
+	linkTTL := time.Duration(cfg.DataExport.LinkTTL) * time.Second
+	if linkTTL <= 0 {
+		linkTTL = 15 * time.Minute
+	}
+
+	retention := time.Duration(cfg.DataExport.RetentionHours) * time.Hour
+	if retention <= 0 {
+		retention = 72 * time.Hour
+	}
+
+	pollInterval := time.Duration(cfg.DataExport.PollInterval) * time.Second
+	if pollInterval <= 0 {
+		pollInterval = 10 * time.Second
+	}
+
+	s := &ExportService{
+		collection:     client.Database(dbName).Collection("data_exports"),
+		userService:    userService,
+		tokenService:   tokenService,
+		apiKeyService:  apiKeyService,
+		auditService:   auditService,
+		consentService: consentService,
+		directory:      directory,
+		signingKey:     []byte(signingKey),
+		linkTTL:        linkTTL,
+		retention:      retention,
+		pollInterval:   pollInterval,
+		publicURL:      cfg.Server.PublicURL,
+	}
+
+	s.sections = []exportSection{
+		{name: "user.json", collect: s.collectUser},
+		{name: "login_history.json", collect: s.collectLoginHistory},
+		{name: "sessions.json", collect: s.collectSessions},
+		{name: "api_keys.json", collect: s.collectAPIKeys},
+		{name: "consents.json", collect: s.collectConsents},
+		{name: "audit_log.json", collect: s.collectAuditLog},
+	}
+
+	return s, nil
+}
+
+// RequestExport queues an export of the user's data on behalf of actorID
+func (s *ExportService) RequestExport(ctx context.Context, userID primitive.ObjectID, actorID string) (*models.DataExport, error) {
+	if _, err := s.userService.GetUserByID(ctx, userID); err != nil {
+		return nil, errors.ErrNotFound
+	}
+
+	export := &models.DataExport{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		RequestedBy: actorID,
+		Status:      models.ExportPending,
+		CreatedAt:   time.Now(),
+	}
+	if _, err := s.collection.InsertOne(ctx, export); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(ctx, models.AuditDataExportRequested, userID, actorID, map[string]interface{}{
+		"export_id": export.ID.Hex(),
+	})
+
+	return export, nil
+}
+
+// GetExport returns an export with a fresh download link once it has completed.
+// A non-nil userID restricts the lookup to that user's exports.
+func (s *ExportService) GetExport(ctx context.Context, exportID primitive.ObjectID, userID *primitive.ObjectID) (*models.DataExport, error) {
+	filter := bson.M{"_id": exportID}
+	if userID != nil {
+		filter["user_id"] = *userID
+	}
+
+	var export models.DataExport
+	if err := s.collection.FindOne(ctx, filter).Decode(&export); err != nil {
+		return nil, ErrExportNotFound
+	}
+
+	if export.Status == models.ExportCompleted {
+		export.DownloadURL = s.signedDownloadURL(export.ID.Hex(), time.Now().Add(s.linkTTL))
+	}
+
+	return &export, nil
+}
+
+// OpenDownload verifies a signed download link and returns the archive path
+func (s *ExportService) OpenDownload(ctx context.Context, exportID, expires, signature string) (string, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return "", ErrInvalidDownloadLink
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(exportID+"."+expires))) {
+		return "", ErrInvalidDownloadLink
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(exportID)
+	if err != nil {
+		return "", ErrExportNotFound
+	}
+
+	var export models.DataExport
+	if err := s.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&export); err != nil {
+		return "", ErrExportNotFound
+	}
+	if export.Status != models.ExportCompleted {
+		return "", ErrExportNotReady
+	}
+
+	return export.FilePath, nil
+}
+
+// RunExportWorker processes queued exports and removes expired archives
+// until ctx is done
+func (s *ExportService) RunExportWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for s.processNext(ctx) {
+		}
+		s.expireArchives(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext claims and builds one pending export, reporting whether one was found
+func (s *ExportService) processNext(ctx context.Context) bool {
+	var export models.DataExport
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"status": models.ExportPending},
+		bson.M{"$set": bson.M{"status": models.ExportRunning}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetReturnDocument(options.After),
+	).Decode(&export)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			logger.WithContext(ctx).Error("Failed to claim data export", zap.Error(err))
+		}
+		return false
+	}
+
+	path, digest, err := s.buildArchive(ctx, &export)
+	if err != nil {
+		logger.WithContext(ctx).Error("Data export failed", zap.String("export_id", export.ID.Hex()), zap.Error(err))
+		s.collection.UpdateOne(ctx, bson.M{"_id": export.ID}, bson.M{"$set": bson.M{
+			"status": models.ExportFailed,
+			"error":  "Export could not be generated",
+		}})
+		return true
+	}
+
+	now := time.Now()
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": export.ID}, bson.M{"$set": bson.M{
+		"status":       models.ExportCompleted,
+		"file_path":    path,
+		"sha256":       digest,
+		"completed_at": now,
+		"expires_at":   now.Add(s.retention),
+	}})
+	if err == nil && result.MatchedCount == 0 {
+		// The user was purged while the archive was being built
+		os.Remove(path)
+	}
+
+	return true
+}
+
+// buildArchive writes the export ZIP to disk and returns its path and SHA-256
+func (s *ExportService) buildArchive(ctx context.Context, export *models.DataExport) (string, string, error) {
+	user, err := s.userService.GetUserByID(ctx, export.UserID)
+	if err != nil {
+		return "", "", err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	manifest := models.ExportManifest{
+		ExportID:    export.ID.Hex(),
+		UserID:      user.ID.Hex(),
+		GeneratedAt: time.Now(),
+		Files:       map[string]string{},
+	}
+
+	for _, section := range s.sections {
+		data, err := section.collect(ctx, user)
+		if err != nil {
+			return "", "", fmt.Errorf("collecting %s: %w", section.name, err)
+		}
+
+		content, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return "", "", err
+		}
+		if err := writeZipFile(archive, section.name, content); err != nil {
+			return "", "", err
+		}
+
+		sum := sha256.Sum256(content)
+		manifest.Files[section.name] = hex.EncodeToString(sum[:])
+	}
+
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	if err := writeZipFile(archive, "manifest.json", manifestContent); err != nil {
+		return "", "", err
+	}
+	if err := writeZipFile(archive, "manifest.sig", []byte(s.sign(string(manifestContent)))); err != nil {
+		return "", "", err
+	}
+	if err := archive.Close(); err != nil {
+		return "", "", err
+	}
+
+	if err := os.MkdirAll(s.directory, 0o700); err != nil {
+		return "", "", err
+	}
+	path := s.archivePath(export.ID)
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return "", "", err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	return path, hex.EncodeToString(sum[:]), nil
+}
+
+// expireArchives deletes archives past their retention period
+func (s *ExportService) expireArchives(ctx context.Context) {
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"status":     models.ExportCompleted,
+		"expires_at": bson.M{"$lte": time.Now()},
+	})
+	if err != nil {
+		logger.WithContext(ctx).Error("Failed to find expired data exports", zap.Error(err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var exports []*models.DataExport
+	if err := cursor.All(ctx, &exports); err != nil {
+		return
+	}
+
+	for _, export := range exports {
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			logger.WithContext(ctx).Error("Failed to remove expired data export", zap.String("export_id", export.ID.Hex()), zap.Error(err))
+			continue
+		}
+		s.collection.UpdateOne(ctx, bson.M{"_id": export.ID}, bson.M{
+			"$set":   bson.M{"status": models.ExportExpired},
+			"$unset": bson.M{"file_path": ""},
+		})
+	}
+}
+
+// DeleteUserExports removes every export archive of a user and the records
+// of their exports
+func (s *ExportService) DeleteUserExports(ctx context.Context, userID primitive.ObjectID) error {
+	cursor, err := s.collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var exports []*models.DataExport
+	if err := cursor.All(ctx, &exports); err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		// Archives are named after the export, so one still being built when
+		// its record was read is removed as well
+		if err := os.Remove(s.archivePath(export.ID)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	_, err = s.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+func (s *ExportService) archivePath(exportID primitive.ObjectID) string {
+	return filepath.Join(s.directory, exportID.Hex()+".zip")
+}
+
+func (s *ExportService) signedDownloadURL(exportID string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query := url.Values{
+		"expires":   {expires},
+		"signature": {s.sign(exportID + "." + expires)},
+	}
+	return s.publicURL + "/api/exports/" + exportID + "/download?" + query.Encode()
+}
+
+func (s *ExportService) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func writeZipFile(archive *zip.Writer, name string, content []byte) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+func (s *ExportService) collectUser(ctx context.Context, user *models.User) (interface{}, error) {
+	return user, nil
+}
+
+func (s *ExportService) collectLoginHistory(ctx context.Context, user *models.User) (interface{}, error) {
+	events, err := s.auditService.ListUserEvents(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	logins := []*models.AuditEvent{}
+	for _, event := range events {
+		if event.Action == models.AuditUserLogin {
+			logins = append(logins, event)
+		}
+	}
+	return logins, nil
+}
+
+func (s *ExportService) collectSessions(ctx context.Context, user *models.User) (interface{}, error) {
+	return s.tokenService.ListUserSessions(ctx, user.ID.Hex())
+}
+
+func (s *ExportService) collectAPIKeys(ctx context.Context, user *models.User) (interface{}, error) {
+	return s.apiKeyService.ListKeys(ctx, user.ID)
+}
+
+func (s *ExportService) collectConsents(ctx context.Context, user *models.User) (interface{}, error) {
+	return s.consentService.ListUserConsents(ctx, user.ID)
+}
+
+func (s *ExportService) collectAuditLog(ctx context.Context, user *models.User) (interface{}, error) {
+	return s.auditService.ListUserEvents(ctx, user.ID)
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/blocklist"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+)
+
+// handlePattern allows 3 to 30 letters, digits and underscores, starting
+// with a letter
+var handlePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{2,29}$`)
+
+// leetReplacer undoes common letter substitutions before profanity matching
+var leetReplacer = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "_", "",
+)
+
+// HandleService manages the optional public handles of users. Released
+// handles keep redirecting to their previous owner for a while, during which
+// nobody else can claim them.
+type HandleService struct {
+	redirects    *mongo.Collection
+	userService  *UserService
+	auditService *AuditService
+	reserved     *blocklist.List
+	profanity    *blocklist.List
+	cooldown     time.Duration
+	redirectTTL  time.Duration
+}
+
+func NewHandleService(client *mongo.Client, dbName string, cfg *config.Config, userService *UserService, auditService *AuditService) (*HandleService, error) {
+	reserved, err := blocklist.Load(cfg.Handles.ReservedFile)
+	if err != nil {
+		return nil, err
+	}
+	profanity, err := blocklist.Load(cfg.Handles.ProfanityFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cooldown := time.Duration(cfg.Handles.ChangeCooldownDays) * 24 * time.Hour
+	if cooldown <= 0 {
+		cooldown = 30 * 24 * time.Hour
+	}
+
+	redirectTTL := time.Duration(cfg.Handles.RedirectDays) * 24 * time.Hour
+	if redirectTTL <= 0 {
+		redirectTTL = 90 * 24 * time.Hour
+	}
+
+	return &HandleService{
+		redirects:    client.Database(dbName).Collection("handle_redirects"),
+		userService:  userService,
+		auditService: auditService,
+		reserved:     reserved,
+		profanity:    profanity,
+		cooldown:     cooldown,
+		redirectTTL:  redirectTTL,
+	}, nil
+}
+
+// EnsureIndexes creates the redirect lookup index and expires old redirects
+func (s *HandleService) EnsureIndexes(ctx context.Context) error {
+	_, err := s.redirects.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "handle", Value: 1}},
+			Options: options.Index().SetCollation(handleCollation),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// CheckAvailability reports whether userID could claim a handle. Pass
+// primitive.NilObjectID for anonymous checks.
+func (s *HandleService) CheckAvailability(ctx context.Context, handle string, userID primitive.ObjectID) (*models.HandleAvailability, error) {
+	availability := &models.HandleAvailability{Handle: handle}
+
+	switch {
+	case !handlePattern.MatchString(handle):
+		availability.Reason = models.HandleInvalid
+	case s.reserved.Contains(handle):
+		availability.Reason = models.HandleReserved
+	case s.profanity.ContainedIn(leetReplacer.Replace(strings.ToLower(handle))):
+		availability.Reason = models.HandleNotAllowed
+	}
+	if availability.Reason != "" {
+		return availability, nil
+	}
+
+	owner, err := s.userService.GetUserByHandle(ctx, handle)
+	if err == nil && owner.ID != userID {
+		availability.Reason = models.HandleTaken
+		return availability, nil
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	redirect, err := s.findRedirect(ctx, handle)
+	if err == nil && redirect.UserID != userID {
+		availability.Reason = models.HandleTaken
+		return availability, nil
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	availability.Available = true
+	return availability, nil
+}
+
+// ChangeHandle gives the user a new handle. Apart from the first one and
+// changes of letter case, handles can only be changed once per cooldown.
+func (s *HandleService) ChangeHandle(ctx context.Context, userID primitive.ObjectID, handle string) (*models.User, error) {
+	user, err := s.userService.loadUser(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrNotFound
+	}
+	if user.Handle == handle {
+		return user, nil
+	}
+
+	caseOnly := strings.EqualFold(user.Handle, handle)
+	if !caseOnly {
+		if err := s.checkCooldown(user); err != nil {
+			return nil, err
+		}
+
+		availability, err := s.CheckAvailability(ctx, handle, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !availability.Available {
+			return nil, errors.New(http.StatusConflict, "Handle is not available", availability.Reason)
+		}
+	}
+
+	previous := user.Handle
+	if err := s.userService.SetHandle(ctx, user, handle); err != nil {
+		return nil, err
+	}
+
+	if !caseOnly {
+		// Reclaiming an old handle ends its redirect
+		if _, err := s.redirects.DeleteMany(
+			ctx,
+			bson.M{"handle": handle, "user_id": userID},
+			options.Delete().SetCollation(handleCollation),
+		); err != nil {
+			logger.WithContext(ctx).Error("Failed to remove handle redirect", zap.Error(err))
+		}
+		s.release(ctx, userID, previous)
+	}
+
+	s.auditService.Record(ctx, models.AuditHandleChanged, userID, userID.Hex(), map[string]interface{}{
+		"from": previous,
+		"to":   handle,
+	})
+
+	return user, nil
+}
+
+// ClearHandle removes the user's handle, which keeps redirecting to them
+// until it expires
+func (s *HandleService) ClearHandle(ctx context.Context, userID primitive.ObjectID) error {
+	user, err := s.userService.loadUser(ctx, userID)
+	if err != nil {
+		return errors.ErrNotFound
+	}
+	if user.Handle == "" {
+		return nil
+	}
+
+	previous := user.Handle
+	if err := s.userService.SetHandle(ctx, user, ""); err != nil {
+		return err
+	}
+	s.release(ctx, userID, previous)
+
+	s.auditService.Record(ctx, models.AuditHandleChanged, userID, userID.Hex(), map[string]interface{}{
+		"from": previous,
+		"to":   "",
+	})
+
+	return nil
+}
+
+// Resolve returns the public profile for a handle. For a recently released
+// handle it returns the owner's current handle to redirect to instead.
+func (s *HandleService) Resolve(ctx context.Context, handle string) (*models.PublicProfile, string, error) {
+	user, err := s.userService.GetUserByHandle(ctx, handle)
+	if err == nil {
+		if !models.StatusAllowsLogin(user.CurrentStatus()) {
+			return nil, "", errors.ErrNotFound
+		}
+		return user.PublicProfile(), "", nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, "", err
+	}
+
+	redirect, err := s.findRedirect(ctx, handle)
+	if err != nil {
+		return nil, "", errors.ErrNotFound
+	}
+	owner, err := s.userService.GetUserByID(ctx, redirect.UserID)
+	if err != nil || owner.Handle == "" || !models.StatusAllowsLogin(owner.CurrentStatus()) {
+		return nil, "", errors.ErrNotFound
+	}
+
+	return nil, owner.Handle, nil
+}
+
+// checkCooldown rejects a change made too soon after the previous one
+func (s *HandleService) checkCooldown(user *models.User) error {
+	if user.HandleChangedAt == nil {
+		return nil
+	}
+
+	next := user.HandleChangedAt.Add(s.cooldown)
+	if time.Now().Before(next) {
+		return errors.New(
+			http.StatusTooManyRequests,
+			"Handle was changed too recently",
+			fmt.Sprintf("the handle can be changed again after %s", next.UTC().Format(time.RFC3339)),
+		)
+	}
+	return nil
+}
+
+// release records a redirect from a handle the user gave up
+func (s *HandleService) release(ctx context.Context, userID primitive.ObjectID, handle string) {
+	if handle == "" {
+		return
+	}
+
+	now := time.Now()
+	redirect := &models.HandleRedirect{
+		Handle:     handle,
+		UserID:     userID,
+		ReleasedAt: now,
+		ExpiresAt:  now.Add(s.redirectTTL),
+	}
+	if _, err := s.redirects.InsertOne(ctx, redirect); err != nil {
+		logger.WithContext(ctx).Error("Failed to record handle redirect", zap.String("user_id", userID.Hex()), zap.Error(err))
+	}
+}
+
+// DeleteUserRedirects removes the redirects of a user whose account is
+// purged, freeing their previous handles at once
+func (s *HandleService) DeleteUserRedirects(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.redirects.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// findRedirect returns the newest unexpired redirect for a handle. Expired
+// redirects are removed by the TTL index, which only runs once a minute.
+func (s *HandleService) findRedirect(ctx context.Context, handle string) (*models.HandleRedirect, error) {
+	var redirect models.HandleRedirect
+	opts := options.FindOne().
+		SetCollation(handleCollation).
+		SetSort(bson.D{{Key: "released_at", Value: -1}})
+	err := s.redirects.FindOne(
+		ctx,
+		bson.M{"handle": handle, "expires_at": bson.M{"$gt": time.Now()}},
+		opts,
+	).Decode(&redirect)
+	if err != nil {
+		return nil, err
+	}
+	return &redirect, nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+)
+
+// KeyRotationService moves encrypted user fields onto the active keyring key.
+// After a new key is made active, older keys can be removed from the keyring
+// once a run reports nothing left to re-encrypt.
+type KeyRotationService struct {
+	userService *UserService
+	interval    time.Duration
+	batchSize   int64
+}
+
+func NewKeyRotationService(cfg *config.Config, userService *UserService) *KeyRotationService {
+	interval := time.Duration(cfg.Encryption.ReencryptInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	batchSize := int64(cfg.Encryption.ReencryptBatchSize)
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	return &KeyRotationService{
+		userService: userService,
+		interval:    interval,
+		batchSize:   batchSize,
+	}
+}
+
+// ReencryptAll re-encrypts users in batches until none are left on an old
+// key, and returns how many were updated
+func (s *KeyRotationService) ReencryptAll(ctx context.Context) (int, error) {
+	total := 0
+	after := primitive.NilObjectID
+	for {
+		updated, next, err := s.userService.ReencryptBatch(ctx, after, s.batchSize)
+		total += updated
+		if err != nil {
+			return total, err
+		}
+		if next.IsZero() || ctx.Err() != nil {
+			return total, nil
+		}
+		after = next
+	}
+}
+
+// RunReencryptionLoop re-encrypts users on every interval until ctx is done
+func (s *KeyRotationService) RunReencryptionLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		updated, err := s.ReencryptAll(ctx)
+		if err != nil {
+			logger.WithContext(ctx).Error("User re-encryption failed", zap.Error(err))
+		} else if updated > 0 {
+			logger.WithContext(ctx).Info("Re-encrypted users", zap.Int("count", updated))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+)
+
+const (
+	qrLoginKeyFormat    = "qr_login:%s"
+	qrLoginPollInterval = time.Second
+)
+
+var (
+	// ErrQRLoginNotFound is returned for unknown or expired QR login sessions
+	ErrQRLoginNotFound = errors.New(http.StatusNotFound, "Login request not found or expired")
+	// ErrQRLoginNotPending is returned when a session was already answered
+	ErrQRLoginNotPending = errors.New(http.StatusConflict, "Login request has already been answered")
+	// ErrQRLoginDenied is returned to the web client when the user rejected the login
+	ErrQRLoginDenied = errors.New(http.StatusForbidden, "Login request was denied")
+)
+
+// QRLoginService runs the cross-device login flow: a web client creates a
+// pending session, an authenticated device approves it and the web client
+// collects its tokens by long-polling.
+type QRLoginService struct {
+	redisClient  *redis.Client
+	userService  *UserService
+	tokenService *TokenService
+	auditService *AuditService
+	ttl          time.Duration
+	pollTimeout  time.Duration
+}
+
+func NewQRLoginService(cfg *config.Config, redisClient *redis.Client, userService *UserService, tokenService *TokenService, auditService *AuditService) *QRLoginService {
+	ttl := time.Duration(cfg.QRLogin.TTL) * time.Second
+	if ttl <= 0 {
+		ttl = 2 * time.Minute
+	}
+
+	pollTimeout := time.Duration(cfg.QRLogin.PollTimeout) * time.Second
+	if pollTimeout <= 0 {
+		pollTimeout = 30 * time.Second
+	}
+
+	return &QRLoginService{
+		redisClient:  redisClient,
+		userService:  userService,
+		tokenService: tokenService,
+		auditService: auditService,
+		ttl:          ttl,
+		pollTimeout:  pollTimeout,
+	}
+}
+
+// CreateSession starts a pending login for the web client at the given address
+func (s *QRLoginService) CreateSession(ctx context.Context, ipAddress, userAgent string) (*models.QRLoginCreated, error) {
+	secret := utils.GenerateRandomToken(32)
+	session := &models.QRLoginSession{
+		ID:         utils.GenerateRandomToken(16),
+		Status:     models.QRLoginPending,
+		SecretHash: utils.HashToken(secret),
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		CreatedAt:  time.Now(),
+		ExpiresAt:  time.Now().Add(s.ttl),
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redisClient.Set(ctx, fmt.Sprintf(qrLoginKeyFormat, session.ID), data, s.ttl).Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.QRLoginCreated{
+		SessionID: session.ID,
+		Secret:    secret,
+		QRPayload: "greeneye://qr-login?session=" + session.ID,
+		ExpiresIn: int64(s.ttl.Seconds()),
+	}, nil
+}
+
+// GetSession returns what the approving device needs to show about a login request
+func (s *QRLoginService) GetSession(ctx context.Context, id string) (*models.QRLoginDetails, error) {
+	session, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.QRLoginDetails{
+		SessionID: session.ID,
+		Status:    session.Status,
+		IPAddress: session.IPAddress,
+		UserAgent: session.UserAgent,
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// Approve lets the web client log in as the approving user
+func (s *QRLoginService) Approve(ctx context.Context, id string, userID primitive.ObjectID) error {
+	return s.answer(ctx, id, models.QRLoginApproved, userID.Hex())
+}
+
+// Deny rejects a pending login request on behalf of the denying user
+func (s *QRLoginService) Deny(ctx context.Context, id string, userID primitive.ObjectID) error {
+	return s.answer(ctx, id, models.QRLoginDenied, userID.Hex())
+}
+
+// WaitForApproval blocks until the session is answered, the poll timeout
+// elapses or ctx is cancelled. On approval it issues an access token exactly
+// once; an empty token with a nil error means the session is still pending.
+func (s *QRLoginService) WaitForApproval(ctx context.Context, id, secret, jkt string) (string, error) {
+	key := fmt.Sprintf(qrLoginKeyFormat, id)
+	deadline := time.NewTimer(s.pollTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(qrLoginPollInterval)
+	defer ticker.Stop()
+
+	for {
+		session, err := s.load(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		if subtle.ConstantTimeCompare([]byte(session.SecretHash), []byte(utils.HashToken(secret))) != 1 {
+			return "", ErrQRLoginNotFound
+		}
+
+		switch session.Status {
+		case models.QRLoginDenied:
+			s.redisClient.Del(ctx, key)
+			return "", ErrQRLoginDenied
+		case models.QRLoginApproved:
+			// Only the poller that removes the session receives the token
+			deleted, err := s.redisClient.Del(ctx, key).Result()
+			if err != nil {
+				return "", err
+			}
+			if deleted == 0 {
+				return "", ErrQRLoginNotFound
+			}
+			return s.issueToken(ctx, session, jkt)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-deadline.C:
+			return "", nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *QRLoginService) issueToken(ctx context.Context, session *models.QRLoginSession, jkt string) (string, error) {
+	objectID, err := primitive.ObjectIDFromHex(session.UserID)
+	if err != nil {
+		return "", ErrQRLoginNotFound
+	}
+
+	user, err := s.userService.GetUserByID(ctx, objectID)
+	if err != nil {
+		return "", ErrQRLoginNotFound
+	}
+	// The account may have been suspended, banned or scheduled for deletion
+	// since the session was approved
+	if err := LoginError(user.CurrentStatus()); err != nil {
+		return "", err
+	}
+
+	token, err := s.tokenService.IssueAccessToken(ctx, user, jkt)
+	if err != nil {
+		return "", err
+	}
+
+	s.auditService.Record(ctx, models.AuditUserLogin, user.ID, user.ID.Hex(), map[string]interface{}{
+		"method":     "qr",
+		"ip_address": session.IPAddress,
+		"user_agent": session.UserAgent,
+	})
+
+	return token, nil
+}
+
+// answer moves a pending session to its final state, keeping its expiry
+func (s *QRLoginService) answer(ctx context.Context, id, status, userID string) error {
+	key := fmt.Sprintf(qrLoginKeyFormat, id)
+
+	return s.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			return ErrQRLoginNotFound
+		} else if err != nil {
+			return err
+		}
+
+		var session models.QRLoginSession
+		if err := json.Unmarshal(data, &session); err != nil {
+			return err
+		}
+		if session.Status != models.QRLoginPending {
+			return ErrQRLoginNotPending
+		}
+
+		session.Status = status
+		session.UserID = userID
+		updated, err := json.Marshal(&session)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, key, updated, redis.SetArgs{KeepTTL: true})
+			return nil
+		})
+		if err == redis.TxFailedErr {
+			return ErrQRLoginNotPending
+		}
+		return err
+	}, key)
+}
+
+func (s *QRLoginService) load(ctx context.Context, id string) (*models.QRLoginSession, error) {
+	data, err := s.redisClient.Get(ctx, fmt.Sprintf(qrLoginKeyFormat, id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrQRLoginNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var session models.QRLoginSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
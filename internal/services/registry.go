@@ -0,0 +1,124 @@
+package services
+
+import (
+	"runtime"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/cache"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/fieldcrypt"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/hashing"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/mailer"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
+)
+
+// Registry holds the service instances shared by the HTTP handlers and the
+// background jobs started from main
+type Registry struct {
+	User    *UserService
+	Token   *TokenService
+	Client  *ClientService
+	DPoP    *DPoPService
+	APIKey  *APIKeyService
+	Auth    *AuthService
+	QRLogin *QRLoginService
+	Audit   *AuditService
+	Account *AccountService
+	Export  *ExportService
+	Consent *ConsentService
+	Status  *StatusService
+	Keys    *KeyRotationService
+	Email   *EmailService
+	Handle  *HandleService
+	Cache   *cache.Store
+	Hashing *hashing.Pool
+	Redis   *redisguard.Guard
+}
+
+func NewRegistry(cfg *config.Config, db *mongo.Client, redisGuard *redisguard.Guard, keyring *fieldcrypt.Keyring) (*Registry, error) {
+	dbName := cfg.MongoDB.Database
+	redisClient := redisGuard.Client()
+
+	cacheStore := cache.NewStore(redisClient, longestCacheTTL(cfg))
+	hashingPool := newHashingPool(cfg)
+	userService := NewUserService(db, dbName, cfg, redisGuard, keyring, cacheStore, hashingPool)
+	tokenService := NewTokenService(cfg, redisGuard)
+	apiKeyService := NewAPIKeyService(db, dbName, userService)
+	auditService := NewAuditService(db, dbName)
+	consentService := NewConsentService(db, dbName, userService)
+	statusService := NewStatusService(db, dbName, userService, tokenService, auditService)
+	emailService, err := NewEmailService(
+		cfg,
+		redisClient,
+		mailer.New(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From),
+		userService,
+		auditService,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	authService := NewAuthService(userService, tokenService, auditService, consentService, emailService, cfg, redisClient)
+
+	handleService, err := NewHandleService(db, dbName, cfg, userService, auditService)
+	if err != nil {
+		return nil, err
+	}
+
+	exportService, err := NewExportService(db, dbName, cfg, userService, tokenService, apiKeyService, auditService, consentService)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Registry{
+		User:    userService,
+		Token:   tokenService,
+		Client:  NewClientService(db, dbName, hashingPool),
+		DPoP:    NewDPoPService(cfg, redisGuard),
+		APIKey:  apiKeyService,
+		Auth:    authService,
+		QRLogin: NewQRLoginService(cfg, redisClient, userService, tokenService, auditService),
+		Audit:   auditService,
+		Account: NewAccountService(cfg, userService, tokenService, apiKeyService, auditService, statusService, authService, emailService, exportService, consentService, handleService),
+		Export:  exportService,
+		Consent: consentService,
+		Status:  statusService,
+		Keys:    NewKeyRotationService(cfg, userService),
+		Email:   emailService,
+		Handle:  handleService,
+		Cache:   cacheStore,
+		Hashing: hashingPool,
+		Redis:   redisGuard,
+	}, nil
+}
+
+// newHashingPool sizes the bcrypt pool from config, defaulting to one hash
+// per CPU
+func newHashingPool(cfg *config.Config) *hashing.Pool {
+	concurrency := cfg.PasswordHashing.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	queueDepth := cfg.PasswordHashing.QueueDepth
+	if queueDepth < 0 {
+		queueDepth = 0
+	}
+	return hashing.NewPool(concurrency, queueDepth)
+}
+
+// longestCacheTTL returns the longest TTL a cached response can have, which
+// is how long its invalidation tags must be kept
+func longestCacheTTL(cfg *config.Config) time.Duration {
+	longest := cfg.Cache.DefaultTTL
+	for _, ttl := range cfg.Cache.RouteTTLs {
+		if ttl > longest {
+			longest = ttl
+		}
+	}
+	if longest <= 0 {
+		longest = 60
+	}
+	return time.Duration(longest) * time.Second
+}
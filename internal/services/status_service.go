@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+)
+
+var (
+	// ErrAccountSuspended blocks login for suspended users
+	ErrAccountSuspended = errors.New(http.StatusForbidden, "Account is suspended")
+	// ErrAccountBanned blocks login for banned users
+	ErrAccountBanned = errors.New(http.StatusForbidden, "Account is banned")
+)
+
+// StatusService moves users between lifecycle states, keeping a history of
+// every change and cutting off access for users who may no longer sign in
+type StatusService struct {
+	history      *mongo.Collection
+	userService  *UserService
+	tokenService *TokenService
+	auditService *AuditService
+}
+
+func NewStatusService(client *mongo.Client, dbName string, userService *UserService, tokenService *TokenService, auditService *AuditService) *StatusService {
+	return &StatusService{
+		history:      client.Database(dbName).Collection("user_status_history"),
+		userService:  userService,
+		tokenService: tokenService,
+		auditService: auditService,
+	}
+}
+
+// ChangeStatus moves a user to a new status on behalf of a staff member.
+// Suspending or banning a user revokes their sessions immediately.
+func (s *StatusService) ChangeStatus(ctx context.Context, userID primitive.ObjectID, req *models.StatusChangeRequest, actorID string) (*models.User, error) {
+	user, err := s.userService.loadUser(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrNotFound
+	}
+
+	from := user.CurrentStatus()
+	if from == req.Status {
+		return user, nil
+	}
+	if from == models.StatusDeleted || !models.CanTransition(from, req.Status) {
+		return nil, errors.New(
+			http.StatusConflict,
+			"Invalid status transition",
+			fmt.Sprintf("cannot change status from %s to %s", from, req.Status),
+		)
+	}
+
+	if err := s.userService.SetStatus(ctx, user, req.Status, req.Reason, actorID); err != nil {
+		return nil, err
+	}
+
+	if models.StatusAllowsLogin(req.Status) {
+		if err := s.tokenService.UnblockUser(ctx, userID.Hex()); err != nil {
+			logger.WithContext(ctx).Error("Failed to unblock user tokens", zap.String("user_id", userID.Hex()), zap.Error(err))
+		}
+	} else {
+		s.cutOffAccess(ctx, userID, req.Status)
+	}
+
+	s.RecordChange(ctx, userID, from, req.Status, req.Reason, actorID)
+
+	return user, nil
+}
+
+// RecordChange stores a status transition in the user's history and audit
+// trail. Failures are logged rather than returned, like audit events.
+func (s *StatusService) RecordChange(ctx context.Context, userID primitive.ObjectID, from, to, reason, actorID string) {
+	change := &models.StatusChange{
+		UserID:     userID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+		ActorID:    actorID,
+		CreatedAt:  time.Now(),
+	}
+
+	if _, err := s.history.InsertOne(ctx, change); err != nil {
+		logger.WithContext(ctx).Error("Failed to record status change",
+			zap.String("user_id", userID.Hex()),
+			zap.String("status", to),
+			zap.Error(err),
+		)
+	}
+
+	s.auditService.Record(ctx, models.AuditStatusChanged, userID, actorID, map[string]interface{}{
+		"from":   from,
+		"to":     to,
+		"reason": reason,
+	})
+}
+
+// PreviousStatus returns the status the user had before it last entered the
+// given status, or fallback when there is no such change on record
+func (s *StatusService) PreviousStatus(ctx context.Context, userID primitive.ObjectID, status, fallback string) string {
+	var change models.StatusChange
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	err := s.history.FindOne(ctx, bson.M{"user_id": userID, "to_status": status}, opts).Decode(&change)
+	if err != nil || change.FromStatus == "" {
+		return fallback
+	}
+	return change.FromStatus
+}
+
+// ListHistory returns the status changes of a user, newest first
+func (s *StatusService) ListHistory(ctx context.Context, userID primitive.ObjectID) ([]*models.StatusChange, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := s.history.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	changes := []*models.StatusChange{}
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// DeleteHistory removes the status changes of a user whose account is purged
+func (s *StatusService) DeleteHistory(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.history.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// LoginError returns the error that blocks a user in the given status from
+// signing in, or nil if they may sign in
+func LoginError(status string) error {
+	switch status {
+	case models.StatusSuspended:
+		return ErrAccountSuspended
+	case models.StatusBanned:
+		return ErrAccountBanned
+	case models.StatusDeleted:
+		return ErrAccountPendingDeletion
+	}
+	return nil
+}
+
+// cutOffAccess rejects the user's outstanding tokens and revokes their sessions
+func (s *StatusService) cutOffAccess(ctx context.Context, userID primitive.ObjectID, status string) {
+	if err := s.tokenService.BlockUser(ctx, userID.Hex(), status); err != nil {
+		logger.WithContext(ctx).Error("Failed to block user tokens", zap.String("user_id", userID.Hex()), zap.Error(err))
+	}
+	if err := s.tokenService.RevokeUserSessions(ctx, userID.Hex()); err != nil {
+		logger.WithContext(ctx).Error("Failed to revoke sessions", zap.String("user_id", userID.Hex()), zap.Error(err))
+	}
+}
@@ -2,15 +2,374 @@
 package services
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+)
+
+const (
+	revokedTokenKeyFormat = "revoked_token:%s"
+	userSessionsKeyFormat = "user_sessions:%s"
+	blockedUserKeyFormat  = "blocked_user:%s"
 )
 
+// ErrAccountInactive rejects tokens of users who were suspended, banned or
+// deleted after the token was issued
+var ErrAccountInactive = errors.New(http.StatusForbidden, "Account is not active")
+
+// Confirmation binds a token to a proof-of-possession key (RFC 7800)
+type Confirmation struct {
+	JKT string `json:"jkt"`
+}
+
+// AccessClaims are the claims carried by access tokens issued by this service.
+type AccessClaims struct {
+	UserID       string        `json:"user_id,omitempty"`
+	ClientID     string        `json:"client_id,omitempty"`
+	Roles        []string      `json:"roles,omitempty"`
+	Scope        string        `json:"scope,omitempty"`
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Scopes returns the space-delimited scope claim as a slice.
+func (c *AccessClaims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// KeyThumbprint returns the DPoP key the token is bound to, if any
+func (c *AccessClaims) KeyThumbprint() string {
+	if c.Confirmation == nil {
+		return ""
+	}
+	return c.Confirmation.JKT
+}
+
+// TokenType returns the scheme the token must be presented with
+func (c *AccessClaims) TokenType() string {
+	if c.KeyThumbprint() != "" {
+		return "DPoP"
+	}
+	return "Bearer"
+}
+
+// confirmationFor binds a token to a DPoP key when a thumbprint is given
+func confirmationFor(jkt string) *Confirmation {
+	if jkt == "" {
+		return nil
+	}
+	return &Confirmation{JKT: jkt}
+}
+
 type TokenService struct {
-	jwtSecret []byte
+	jwtSecret        []byte
+	expiration       time.Duration
+	clientExpiration time.Duration
+	redisClient      *redis.Client
+	redisGuard       *redisguard.Guard
+}
+
+func NewTokenService(cfg *config.Config, redisGuard *redisguard.Guard) *TokenService {
+	expiration := time.Duration(cfg.JWT.Expiration) * time.Hour
+	if expiration <= 0 {
+		expiration = 72 * time.Hour
+	}
+
+	clientExpiration := time.Duration(cfg.OAuth.ClientTokenExpiration) * time.Minute
+	if clientExpiration <= 0 {
+		clientExpiration = time.Hour
+	}
+
+	return &TokenService{
+		jwtSecret:        []byte(cfg.JWT.Secret),
+		expiration:       expiration,
+		clientExpiration: clientExpiration,
+		redisClient:      redisGuard.Client(),
+		redisGuard:       redisGuard,
+	}
+}
+
+// IssueAccessToken signs a new access token for the given user and records
+// it in the user's session index. A non-empty jkt binds the token to that
+// DPoP key.
+func (s *TokenService) IssueAccessToken(ctx context.Context, user *models.User, jkt string) (string, error) {
+	now := time.Now()
+	claims := AccessClaims{
+		UserID:       user.ID.Hex(),
+		Roles:        user.Roles,
+		Confirmation: confirmationFor(jkt),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID.Hex(),
+			ID:        utils.GenerateRandomToken(16),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.expiration)),
+		},
+	}
+
+	token, err := s.sign(&claims)
+	if err != nil {
+		return "", err
+	}
+
+	// While Redis is down the token can still be revoked on its own, but
+	// not by signing out of every session
+	if err := s.redisGuard.Degrade(redisguard.FeatureSessions, s.trackSession(ctx, user.ID.Hex(), &claims)); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ListUserSessions returns the unexpired access tokens issued to a user
+func (s *TokenService) ListUserSessions(ctx context.Context, userID string) ([]*models.Session, error) {
+	key := fmt.Sprintf(userSessionsKeyFormat, userID)
+	entries, err := s.redisClient.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*models.Session, 0, len(entries))
+	for _, entry := range entries {
+		tokenID, _ := entry.Member.(string)
+		sessions = append(sessions, &models.Session{
+			TokenID:   tokenID,
+			ExpiresAt: time.Unix(int64(entry.Score), 0),
+		})
+	}
+
+	return sessions, nil
+}
+
+// RevokeUserSessions revokes every unexpired access token issued to a user
+func (s *TokenService) RevokeUserSessions(ctx context.Context, userID string) error {
+	sessions, err := s.ListUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.redisClient.Pipeline()
+	for _, session := range sessions {
+		if ttl := time.Until(session.ExpiresAt); ttl > 0 {
+			pipe.Set(ctx, fmt.Sprintf(revokedTokenKeyFormat, session.TokenID), "1", ttl)
+		}
+	}
+	pipe.Del(ctx, fmt.Sprintf(userSessionsKeyFormat, userID))
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// trackSession adds a token to the user's session index, dropping entries
+// that have already expired
+func (s *TokenService) trackSession(ctx context.Context, userID string, claims *AccessClaims) error {
+	key := fmt.Sprintf(userSessionsKeyFormat, userID)
+	now := time.Now()
+
+	pipe := s.redisClient.Pipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(claims.ExpiresAt.Unix()), Member: claims.ID})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Unix(), 10))
+	pipe.Expire(ctx, key, s.expiration)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// IssueClientToken signs a scoped access token for a service client acting
+// on its own behalf. A non-empty jkt binds the token to that DPoP key.
+func (s *TokenService) IssueClientToken(client *models.ServiceClient, scopes []string, jkt string) (*models.TokenResponse, error) {
+	now := time.Now()
+	claims := AccessClaims{
+		ClientID:     client.ClientID,
+		Scope:        strings.Join(scopes, " "),
+		Confirmation: confirmationFor(jkt),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   client.ClientID,
+			ID:        utils.GenerateRandomToken(16),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.clientExpiration)),
+		},
+	}
+
+	token, err := s.sign(&claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		AccessToken: token,
+		TokenType:   claims.TokenType(),
+		ExpiresIn:   int64(s.clientExpiration.Seconds()),
+		Scope:       claims.Scope,
+	}, nil
+}
+
+// ParseAccessToken verifies the signature and expiry of an access token and
+// rejects tokens that have been revoked
+func (s *TokenService) ParseAccessToken(ctx context.Context, tokenString string) (*AccessClaims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.IsRevoked(ctx, claims.ID)
+	if err = s.redisGuard.Degrade(redisguard.FeatureTokenRevocation, err); err == redisguard.ErrUnavailable {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New(http.StatusUnauthorized, "Failed to check token revocation")
+	}
+	if revoked {
+		return nil, errors.New(http.StatusUnauthorized, "Token has been revoked")
+	}
+
+	if claims.UserID != "" {
+		blocked, err := s.IsUserBlocked(ctx, claims.UserID)
+		if err = s.redisGuard.Degrade(redisguard.FeatureTokenRevocation, err); err == redisguard.ErrUnavailable {
+			return nil, err
+		}
+		if err != nil {
+			return nil, errors.New(http.StatusUnauthorized, "Failed to check account status")
+		}
+		if blocked {
+			return nil, ErrAccountInactive
+		}
+	}
+
+	return claims, nil
+}
+
+// BlockUser rejects every token of the user, including ones not tracked in
+// the session index, until UnblockUser is called
+func (s *TokenService) BlockUser(ctx context.Context, userID, status string) error {
+	return s.redisClient.Set(ctx, fmt.Sprintf(blockedUserKeyFormat, userID), status, 0).Err()
+}
+
+// UnblockUser lets the user's newly issued tokens be accepted again
+func (s *TokenService) UnblockUser(ctx context.Context, userID string) error {
+	return s.redisClient.Del(ctx, fmt.Sprintf(blockedUserKeyFormat, userID)).Err()
+}
+
+// IsUserBlocked reports whether the user's tokens are currently rejected
+func (s *TokenService) IsUserBlocked(ctx context.Context, userID string) (bool, error) {
+	n, err := s.redisClient.Exists(ctx, fmt.Sprintf(blockedUserKeyFormat, userID)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// RevokeToken marks an access token as revoked until it would have expired.
+// Client tokens can only be revoked by the client they were issued to. User
+// tokens are presented to downstream services rather than issued to them, so
+// any authenticated client holding one may revoke it, for example when the
+// user signs out there. Tokens that cannot be parsed or were issued to
+// another client are ignored, as required by RFC 7009.
+func (s *TokenService) RevokeToken(ctx context.Context, tokenString, clientID string) error {
+	claims, err := s.parse(tokenString)
+	if err != nil || claims.ID == "" {
+		return nil
+	}
+	if claims.ClientID != "" && claims.ClientID != clientID {
+		return nil
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+
+	pipe := s.redisClient.Pipeline()
+	pipe.Set(ctx, fmt.Sprintf(revokedTokenKeyFormat, claims.ID), "1", ttl)
+	if claims.UserID != "" {
+		pipe.ZRem(ctx, fmt.Sprintf(userSessionsKeyFormat, claims.UserID), claims.ID)
+	}
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// IsRevoked reports whether the token with the given ID has been revoked
+func (s *TokenService) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if tokenID == "" {
+		return false, nil
+	}
+
+	n, err := s.redisClient.Exists(ctx, fmt.Sprintf(revokedTokenKeyFormat, tokenID)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Introspect describes an access token as specified by RFC 7662. Any token
+// that fails validation is reported as inactive without further detail.
+func (s *TokenService) Introspect(ctx context.Context, tokenString string) *models.TokenIntrospection {
+	claims, err := s.ParseAccessToken(ctx, tokenString)
+	if err != nil {
+		return &models.TokenIntrospection{Active: false}
+	}
+
+	result := &models.TokenIntrospection{
+		Active:    true,
+		Subject:   claims.Subject,
+		Roles:     claims.Roles,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: claims.TokenType(),
+		TokenID:   claims.ID,
+	}
+	if jkt := claims.KeyThumbprint(); jkt != "" {
+		result.Confirmation = &models.TokenConfirmation{JKT: jkt}
+	}
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Unix()
+	}
+
+	return result
+}
+
+func (s *TokenService) sign(claims *AccessClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.jwtSecret)
+}
+
+func (s *TokenService) parse(tokenString string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// Ensure the signing method is HMAC
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.ErrUnauthorized
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New(http.StatusUnauthorized, "Invalid token")
+	}
+
+	// Tokens issued before subjects were added only carry user_id
+	if claims.Subject == "" {
+		claims.Subject = claims.UserID
+	}
+
+	return claims, nil
 }
 
 func (s *TokenService) GenerateAuthToken(user *models.User) (string, error) {
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/metrics"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
+)
+
+const (
+	userCacheKeyFormat       = "user_cache:id:%s"
+	userMobileCacheKeyFormat = "user_cache:mobile:%s"
+
+	// userCacheMissing is stored for lookups that found no user
+	userCacheMissing = "-"
+
+	// userCacheFilling prefixes the marker a load leaves under its key
+	userCacheFilling = "~filling:"
+
+	// userCacheLoadTimeout bounds a load shared by several callers, which
+	// outlives the request that started it
+	userCacheLoadTimeout = 5 * time.Second
+)
+
+// storeIfFilling replaces a load's marker with the loaded value. A write that
+// forgot the key while the load ran has deleted the marker, so a value read
+// before that write is dropped instead of being cached.
+var storeIfFilling = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+end
+return false
+`)
+
+// userCache is a read-through cache in front of the users collection. It
+// stores raw user documents, so PII stays encrypted in Redis as it is in
+// Mongo. Concurrent misses for the same key share a single load, and lookups
+// that found nothing are remembered for a shorter time.
+type userCache struct {
+	redisClient *redis.Client
+	redisGuard  *redisguard.Guard
+	ttl         time.Duration
+	negativeTTL time.Duration
+	group       singleflight.Group
+}
+
+func newUserCache(redisGuard *redisguard.Guard, ttl, negativeTTL time.Duration) *userCache {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	if negativeTTL <= 0 {
+		negativeTTL = 30 * time.Second
+	}
+
+	return &userCache{
+		redisClient: redisGuard.Client(),
+		redisGuard:  redisGuard,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// get returns the value cached under key, calling load on a miss. A load
+// that finds nothing must return mongo.ErrNoDocuments, which get returns for
+// cached misses as well.
+func (c *userCache) get(ctx context.Context, key string, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	value, err := c.redisClient.Get(ctx, key).Bytes()
+	if err == nil && bytes.HasPrefix(value, []byte(userCacheFilling)) {
+		// Another load is running; its result is not shared across instances
+		err = redis.Nil
+	}
+	if err == nil {
+		metrics.CacheRequests.WithLabelValues(metrics.CacheUsers, metrics.CacheHit).Inc()
+		if string(value) == userCacheMissing {
+			return nil, mongo.ErrNoDocuments
+		}
+		return value, nil
+	}
+	switch err := c.redisGuard.Degrade(redisguard.FeatureUserCache, err); err {
+	case nil, redis.Nil:
+	case redisguard.ErrUnavailable:
+		return nil, err
+	default:
+		logger.WithContext(ctx).Error("User cache read failed", zap.Error(err))
+	}
+	metrics.CacheRequests.WithLabelValues(metrics.CacheUsers, metrics.CacheMiss).Inc()
+
+	// The load is shared by every caller waiting on the key, so the first
+	// caller going away must not cancel it for the others
+	result := c.group.DoChan(key, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), userCacheLoadTimeout)
+		defer cancel()
+		return c.fill(loadCtx, key, load)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]byte), nil
+	}
+}
+
+// fill loads a value and stores it, or a marker if nothing was found. The
+// value is only stored if no write forgot the key during the load.
+func (c *userCache) fill(ctx context.Context, key string, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	marker := userCacheFilling + utils.GenerateRandomToken(16)
+	claimed, err := c.redisClient.SetNX(ctx, key, marker, userCacheLoadTimeout).Result()
+	if err != nil && err != redisguard.ErrUnavailable {
+		logger.WithContext(ctx).Error("User cache write failed", zap.Error(err))
+	}
+
+	value, err := load(ctx)
+	if err == mongo.ErrNoDocuments {
+		if claimed {
+			c.set(ctx, key, marker, []byte(userCacheMissing), c.negativeTTL)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if claimed {
+		c.set(ctx, key, marker, value, c.ttl)
+	}
+	return value, nil
+}
+
+// set stores value under key if the key still holds this load's marker
+func (c *userCache) set(ctx context.Context, key, marker string, value []byte, ttl time.Duration) {
+	err := storeIfFilling.Run(ctx, c.redisClient, []string{key}, marker, value, ttl.Milliseconds()).Err()
+	// Writes are skipped quietly while Redis is down or after the key was
+	// forgotten
+	if err != nil && err != redis.Nil && err != redisguard.ErrUnavailable {
+		logger.WithContext(ctx).Error("User cache write failed", zap.Error(err))
+	}
+}
+
+// forget drops cached values. Failures are logged; the values still expire
+// with their TTL.
+func (c *userCache) forget(ctx context.Context, keys ...string) {
+	if err := c.redisClient.Del(ctx, keys...).Err(); err != nil {
+		logger.WithContext(ctx).Error("User cache invalidation failed", zap.Error(err))
+	}
+}
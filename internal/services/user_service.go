@@ -3,99 +3,920 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 
+	"github.com/greeneye-foundation/greeneye-be-user/internal/config"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/models"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/cache"
+	apperrors "github.com/greeneye-foundation/greeneye-be-user/internal/pkg/errors"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/fieldcrypt"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/hashing"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/logger"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/phone"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/redisguard"
+	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/tracing"
 	"github.com/greeneye-foundation/greeneye-be-user/internal/pkg/utils"
 )
 
+var (
+	// ErrVersionConflict is returned when a user document changed since it was read
+	ErrVersionConflict = apperrors.New(http.StatusPreconditionFailed, "User was modified by another request")
+	// ErrEmailTaken is returned when another user already verified the email
+	ErrEmailTaken = apperrors.New(http.StatusConflict, "Email address is already in use")
+	// ErrHandleTaken is returned when another user already holds the handle
+	ErrHandleTaken = apperrors.New(http.StatusConflict, "Handle is already taken")
+
+	errUserNotFound       = errors.New("user not found")
+	errInvalidCredentials = errors.New("invalid credentials")
+)
+
+// handleCollation compares handles case-insensitively
+var handleCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// Names of the encrypted fields for key derivation
+const (
+	mobileNumberField = "mobile_number"
+	emailField        = "email"
+)
+
+// UserService stores users with their mobile number encrypted at rest. The
+// number is looked up through a blind index; documents written before
+// encryption keep a plaintext mobile_number until they are re-encrypted.
+// Lookups by ID and mobile number are read through a Redis cache. Every write
+// invalidates the cached user and the cached responses that include it.
 type UserService struct {
 	collection *mongo.Collection
+	keyring    *fieldcrypt.Keyring
+	cacheStore *cache.Store
+	users      *userCache
+	hasher     *hashing.Pool
 }
 
-func NewUserService(client *mongo.Client, dbName string) *UserService {
+func NewUserService(client *mongo.Client, dbName string, cfg *config.Config, redisGuard *redisguard.Guard, keyring *fieldcrypt.Keyring, cacheStore *cache.Store, hasher *hashing.Pool) *UserService {
 	return &UserService{
 		collection: client.Database(dbName).Collection("users"),
+		keyring:    keyring,
+		cacheStore: cacheStore,
+		hasher:     hasher,
+		users: newUserCache(
+			redisGuard,
+			time.Duration(cfg.Cache.UserTTL)*time.Second,
+			time.Duration(cfg.Cache.UserNegativeTTL)*time.Second,
+		),
+	}
+}
+
+// invalidate drops the cached user and the cached responses that include it.
+// Failures are logged; the entries still expire with their TTL.
+func (s *UserService) invalidate(ctx context.Context, id primitive.ObjectID) {
+	s.users.forget(ctx, fmt.Sprintf(userCacheKeyFormat, id.Hex()))
+	if err := s.cacheStore.Invalidate(ctx, cache.UserTag(id.Hex()), cache.UsersTag); err != nil {
+		logger.WithContext(ctx).Error("Failed to invalidate cached user responses", zap.String("user_id", id.Hex()), zap.Error(err))
+	}
+}
+
+// EnsureIndexes creates the indexes user lookups rely on
+func (s *UserService) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		uniqueIndex("mobile_number_index"),
+		uniqueIndex("email_index"),
+		{
+			Keys: bson.D{{Key: "handle", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetCollation(handleCollation).
+				SetPartialFilterExpression(bson.M{"handle": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+// uniqueIndex enforces uniqueness of a field on documents that have it
+func uniqueIndex(field string) mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{{Key: field, Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{field: bson.M{"$exists": true}}),
 	}
 }
 
 func (s *UserService) CreateUser(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
 	// Hash password
-	hashedPassword, err := utils.HashPassword(user.PasswordHash)
+	hashedPassword, err := s.hasher.Hash(ctx, user.PasswordHash)
 	if err != nil {
 		return err
 	}
 	user.PasswordHash = hashedPassword
+	user.Version = 1
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+
+	// Encrypt PII before it is written
+	doc, err := s.seal(user)
+	if err != nil {
+		return err
+	}
+	user.MobileNumberEnc = doc.MobileNumberEnc
+	user.MobileNumberIndex = doc.MobileNumberIndex
 
 	// Insert user
-	_, err = s.collection.InsertOne(ctx, user)
-	return err
+	if _, err := s.collection.InsertOne(ctx, doc); err != nil {
+		return err
+	}
+
+	// A lookup may have cached that the number was not registered
+	s.users.forget(ctx, fmt.Sprintf(userMobileCacheKeyFormat, doc.MobileNumberIndex))
+	s.invalidate(ctx, user.ID)
+	return nil
+}
+
+// removeUser deletes a user whose registration could not be completed
+func (s *UserService) removeUser(ctx context.Context, user *models.User) error {
+	if _, err := s.collection.DeleteOne(ctx, bson.M{"_id": user.ID}); err != nil {
+		return err
+	}
+
+	s.users.forget(ctx, fmt.Sprintf(userMobileCacheKeyFormat, user.MobileNumberIndex))
+	s.invalidate(ctx, user.ID)
+	return nil
 }
 
+// AuthenticateUser checks the password of the user identified by email when
+// one is given, or by mobile number otherwise
 func (s *UserService) AuthenticateUser(ctx context.Context, login *models.UserLogin) (*models.User, error) {
-	var user models.User
-	err := s.collection.FindOne(ctx, bson.M{"mobile_number": login.MobileNumber}).Decode(&user)
+	ctx, span := tracing.Start(ctx, "UserService.AuthenticateUser")
+	defer span.End()
+
+	filter := s.mobileNumberFilter(login.MobileNumber, login.CountryCode)
+	if login.Email != "" {
+		filter = s.emailFilter(login.Email)
+	}
+
+	user, err := s.findOne(ctx, filter)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, errUserNotFound
 	}
 
 	// Compare passwords
-	if err := utils.CheckPasswordHash(login.Password, user.PasswordHash); err != nil {
-		return nil, errors.New("invalid credentials")
+	if err := s.CheckPassword(ctx, user, login.Password); err != nil {
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return nil, errInvalidCredentials
+		}
+		return nil, err
 	}
 
-	return &user, nil
+	return user, nil
 }
 
-func (s *UserService) GetUserByMobileNumber(ctx context.Context, mobileNumber string) (*models.User, error) {
-	var user models.User
-	err := s.collection.FindOne(ctx, bson.M{"mobile_number": mobileNumber}).Decode(&user)
+// HashPassword hashes a new password on the shared hashing pool
+func (s *UserService) HashPassword(ctx context.Context, password string) (string, error) {
+	ctx, span := tracing.Start(ctx, "UserService.HashPassword")
+	defer span.End()
+
+	return s.hasher.Hash(ctx, password)
+}
+
+// CheckPassword verifies the user's password on the shared hashing pool. It
+// returns bcrypt.ErrMismatchedHashAndPassword for a wrong password, and
+// hashing.ErrBusy when the server is too loaded to check it.
+func (s *UserService) CheckPassword(ctx context.Context, user *models.User, password string) error {
+	ctx, span := tracing.Start(ctx, "UserService.CheckPassword")
+	defer span.End()
+
+	return s.hasher.Compare(ctx, password, user.PasswordHash)
+}
+
+// GetUserByMobileNumber finds a user through the cached mapping from the
+// number's blind index to the user's ID. The country is optional, see
+// phone.Normalize.
+func (s *UserService) GetUserByMobileNumber(ctx context.Context, mobileNumber, country string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByMobileNumber")
+	defer span.End()
+
+	filter := s.mobileNumberFilter(mobileNumber, country)
+	index := s.mobileNumberIndex(mobileNumber, country)
+	key := fmt.Sprintf(userMobileCacheKeyFormat, index)
+
+	value, err := s.users.get(ctx, key, func(ctx context.Context) ([]byte, error) {
+		raw, err := s.collection.FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"_id": 1})).Raw()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(raw.Lookup("_id").ObjectID().Hex()), nil
+	})
 	if err != nil {
 		return nil, err
 	}
+
+	id, err := primitive.ObjectIDFromHex(string(value))
+	if err == nil {
+		user, err := s.GetUserByID(ctx, id)
+		if err == nil && (user.MobileNumberIndex == index || user.MobileNumber == mobileNumber) {
+			return user, nil
+		}
+	}
+
+	// The user was purged or changed number since the mapping was cached
+	s.users.forget(ctx, key)
+	return s.findOne(ctx, filter)
+}
+
+// GetUserByEmail finds the user who verified the given email address
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByEmail")
+	defer span.End()
+
+	return s.findOne(ctx, s.emailFilter(email))
+}
+
+// GetUserByHandle finds the user currently holding a handle, ignoring case
+func (s *UserService) GetUserByHandle(ctx context.Context, handle string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByHandle")
+	defer span.End()
+
+	var user models.User
+	opts := options.FindOne().SetCollation(handleCollation)
+	if err := s.collection.FindOne(ctx, bson.M{"handle": handle}, opts).Decode(&user); err != nil {
+		return nil, err
+	}
+	if err := s.open(&user); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
+// SetHandle gives the user a new handle, or removes it when handle is empty,
+// if the user has not changed since it was read
+func (s *UserService) SetHandle(ctx context.Context, user *models.User, handle string) error {
+	ctx, span := tracing.Start(ctx, "UserService.SetHandle")
+	defer span.End()
+
+	now := time.Now()
+	set := bson.M{"handle_changed_at": now, "updated_at": now}
+	change := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if handle == "" {
+		change["$unset"] = bson.M{"handle": ""}
+	} else {
+		set["handle"] = handle
+	}
+
+	result, err := s.collection.UpdateOne(ctx, versionFilter(user.ID, user.Version), change)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrHandleTaken
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+
+	user.Handle = handle
+	user.HandleChangedAt = &now
+	user.UpdatedAt = now
+	user.Version++
+	s.invalidate(ctx, user.ID)
+	return nil
+}
+
+// GetUserByID returns a user through the cache, without its password hash
 func (s *UserService) GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
-	var user models.User
-	err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+	defer span.End()
+
+	raw, err := s.users.get(ctx, fmt.Sprintf(userCacheKeyFormat, id.Hex()), func(ctx context.Context) ([]byte, error) {
+		opts := options.FindOne().SetProjection(bson.M{"password_hash": 0})
+		return s.collection.FindOne(ctx, bson.M{"_id": id}, opts).Raw()
+	})
 	if err != nil {
 		return nil, err
 	}
+
+	var user models.User
+	if err := bson.Unmarshal(raw, &user); err != nil {
+		return nil, err
+	}
+	if err := s.open(&user); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
-func (s *UserService) UpdateUser(ctx context.Context, user *models.User) error {
+// loadUser reads a user from Mongo, bypassing the cache. It is used where the
+// password hash is needed and before writes that compare the version.
+func (s *UserService) loadUser(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+// findOne decodes and decrypts the first user matching filter
+func (s *UserService) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	var user models.User
+	if err := s.collection.FindOne(ctx, filter).Decode(&user); err != nil {
+		return nil, err
+	}
+	if err := s.open(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// findMany decodes and decrypts every user matching filter
+func (s *UserService) findMany(ctx context.Context, filter bson.M) ([]*models.User, error) {
+	cursor, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if err := s.open(user); err != nil {
+			return nil, err
+		}
+	}
+
+	return users, nil
+}
+
+// mobileNumberFilter matches a mobile number by the blind index of its
+// canonical form, or in plaintext on documents that have not been encrypted
+// yet. Without a country the number must be in international format.
+func (s *UserService) mobileNumberFilter(mobileNumber, country string) bson.M {
+	canonical, err := phone.Normalize(mobileNumber, country)
+	if err != nil {
+		canonical = mobileNumber
+	}
+
+	return bson.M{"$or": bson.A{
+		bson.M{"mobile_number_index": s.keyring.BlindIndex(mobileNumberField, canonical)},
+		bson.M{"mobile_number": bson.M{"$in": bson.A{canonical, mobileNumber}}},
+	}}
+}
+
+// mobileNumberIndex returns the blind index of a number's canonical form
+func (s *UserService) mobileNumberIndex(mobileNumber, country string) string {
+	canonical, err := phone.Normalize(mobileNumber, country)
+	if err != nil {
+		canonical = mobileNumber
+	}
+	return s.keyring.BlindIndex(mobileNumberField, canonical)
+}
+
+// emailFilter matches a verified email address by its blind index
+func (s *UserService) emailFilter(email string) bson.M {
+	return bson.M{"email_index": s.keyring.BlindIndex(emailField, NormalizeEmail(email))}
+}
+
+// NormalizeEmail returns the form in which email addresses are compared
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// SetVerifiedEmail stores a verified email address for the user, failing
+// with ErrEmailTaken if another user already verified it
+func (s *UserService) SetVerifiedEmail(ctx context.Context, id primitive.ObjectID, email string) error {
+	ctx, span := tracing.Start(ctx, "UserService.SetVerifiedEmail")
+	defer span.End()
+
+	email = NormalizeEmail(email)
+	env, err := s.keyring.Encrypt(emailField, email)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": bson.M{
+				"email_enc":         env,
+				"email_index":       s.keyring.BlindIndex(emailField, email),
+				"email_verified_at": now,
+				"updated_at":        now,
+			},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return apperrors.ErrNotFound
+	}
+
+	s.invalidate(ctx, id)
+	return nil
+}
+
+// RemoveEmail removes the user's email address
+func (s *UserService) RemoveEmail(ctx context.Context, id primitive.ObjectID) error {
+	ctx, span := tracing.Start(ctx, "UserService.RemoveEmail")
+	defer span.End()
+
 	_, err := s.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": user.ID},
+		bson.M{"_id": id},
+		bson.M{
+			"$unset": bson.M{"email_enc": "", "email_index": "", "email_verified_at": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$inc":   bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, id)
+	return nil
+}
+
+// seal returns a copy of the user with its PII encrypted for storage
+func (s *UserService) seal(user *models.User) (*models.User, error) {
+	env, err := s.keyring.Encrypt(mobileNumberField, user.MobileNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := *user
+	doc.MobileNumber = ""
+	doc.MobileNumberEnc = env
+	doc.MobileNumberIndex = s.keyring.BlindIndex(mobileNumberField, user.MobileNumber)
+	return &doc, nil
+}
+
+// open decrypts the user's PII in place
+func (s *UserService) open(user *models.User) error {
+	// Documents that predate encryption keep the plaintext number
+	if user.MobileNumberEnc != nil {
+		mobileNumber, err := s.keyring.Decrypt(mobileNumberField, user.MobileNumberEnc)
+		if err != nil {
+			return fmt.Errorf("decrypting mobile number of user %s: %w", user.ID.Hex(), err)
+		}
+		user.MobileNumber = mobileNumber
+	}
+
+	if user.EmailEnc != nil {
+		email, err := s.keyring.Decrypt(emailField, user.EmailEnc)
+		if err != nil {
+			return fmt.Errorf("decrypting email of user %s: %w", user.ID.Hex(), err)
+		}
+		user.Email = email
+	}
+	return nil
+}
+
+// ReencryptBatch moves up to limit users after the given ID onto the active
+// key: envelopes wrapped by an older key are re-wrapped and plaintext numbers
+// are canonicalized and encrypted. Users whose data cannot be decrypted or
+// whose number cannot be parsed are logged and left as they are. It returns
+// how many users were updated and the ID to continue after, which is zero
+// once no users are left.
+func (s *UserService) ReencryptBatch(ctx context.Context, after primitive.ObjectID, limit int64) (int, primitive.ObjectID, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ReencryptBatch")
+	defer span.End()
+
+	activeKeyID := s.keyring.ActiveKeyID()
+	filter := bson.M{
+		"_id": bson.M{"$gt": after},
+		"$or": bson.A{
+			bson.M{"mobile_number_enc.key_id": bson.M{"$exists": true, "$ne": activeKeyID}},
+			bson.M{"email_enc.key_id": bson.M{"$exists": true, "$ne": activeKeyID}},
+			bson.M{"mobile_number": bson.M{"$exists": true, "$ne": ""}},
+		},
+	}
+
+	var users []*models.User
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, primitive.NilObjectID, err
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, &users); err != nil {
+		return 0, primitive.NilObjectID, err
+	}
+
+	updated := 0
+	for _, user := range users {
+		log := logger.WithContext(ctx).With(zap.String("user_id", user.ID.Hex()))
+		match := bson.M{"_id": user.ID}
+		set := bson.M{}
+		change := bson.M{"$set": set}
+		forget := []string{fmt.Sprintf(userCacheKeyFormat, user.ID.Hex())}
+
+		switch {
+		case user.MobileNumber != "":
+			canonical, err := canonicalMobileNumber(user)
+			if err != nil {
+				log.Warn("Skipping re-encryption of unparseable mobile number", zap.Error(err))
+				continue
+			}
+			doc, err := s.seal(&models.User{MobileNumber: canonical})
+			if err != nil {
+				return updated, primitive.NilObjectID, err
+			}
+			match["mobile_number"] = user.MobileNumber
+			set["mobile_number_enc"] = doc.MobileNumberEnc
+			set["mobile_number_index"] = doc.MobileNumberIndex
+			change["$unset"] = bson.M{"mobile_number": ""}
+			// Lookups of the canonical number may have cached a miss
+			forget = append(forget, fmt.Sprintf(userMobileCacheKeyFormat, doc.MobileNumberIndex))
+		case user.MobileNumberEnc != nil && s.keyring.NeedsRewrap(user.MobileNumberEnc):
+			env, err := s.keyring.Rewrap(user.MobileNumberEnc)
+			if err != nil {
+				log.Error("Skipping re-encryption of undecryptable mobile number", zap.Error(err))
+				continue
+			}
+			match["mobile_number_enc.key_id"] = user.MobileNumberEnc.KeyID
+			set["mobile_number_enc"] = env
+		}
+
+		if user.EmailEnc != nil && s.keyring.NeedsRewrap(user.EmailEnc) {
+			env, err := s.keyring.Rewrap(user.EmailEnc)
+			if err != nil {
+				log.Error("Skipping re-encryption of undecryptable email", zap.Error(err))
+				continue
+			}
+			match["email_enc.key_id"] = user.EmailEnc.KeyID
+			set["email_enc"] = env
+		}
+
+		result, err := s.collection.UpdateOne(ctx, match, change)
+		if mongo.IsDuplicateKeyError(err) {
+			// Another user already has the canonical form of the number
+			log.Error("Skipping re-encryption of duplicate mobile number", zap.Error(err))
+			continue
+		}
+		if err != nil {
+			return updated, primitive.NilObjectID, err
+		}
+		if result.ModifiedCount > 0 {
+			// Cached documents may still be wrapped by a retired key
+			s.users.forget(ctx, forget...)
+		}
+		updated += int(result.ModifiedCount)
+	}
+
+	if int64(len(users)) < limit {
+		return updated, primitive.NilObjectID, nil
+	}
+	return updated, users[len(users)-1].ID, nil
+}
+
+// canonicalMobileNumber returns the E.164 form of a plaintext number stored
+// before numbers were normalized, so that it gets the same blind index as
+// lookups of the number
+func canonicalMobileNumber(user *models.User) (string, error) {
+	return phone.Normalize(user.MobileNumber, user.CountryCode)
+}
+
+// UpdateUser writes the user only if it has not changed since it was read,
+// and bumps its version on success
+func (s *UserService) UpdateUser(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
+	result, err := s.collection.UpdateOne(
+		ctx,
+		versionFilter(user.ID, user.Version),
 		bson.M{
 			"$set": bson.M{
 				"password_hash": user.PasswordHash,
+				"last_login_at": user.LastLoginAt,
 				"updated_at":    user.UpdatedAt,
-				// Add other fields as necessary
 			},
+			"$inc": bson.M{"version": 1},
 		},
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+
+	user.Version++
+	s.invalidate(ctx, user.ID)
+	return nil
 }
 
-func (s *UserService) GetUsers(ctx context.Context) ([]*models.User, error) {
-	collection := s.collection.Database().Collection("users")
+// versionFilter matches a user document at the given version
+func versionFilter(id primitive.ObjectID, version int64) bson.M {
+	if version == 0 {
+		// Documents written before versioning have no version field
+		return bson.M{"_id": id, "version": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"_id": id, "version": version}
+}
+
+// UpdateProfile validates and applies profile changes to the user at the
+// expected version, writing only the fields whose values actually differ from
+// the stored document. A negative expectedVersion skips the version check.
+func (s *UserService) UpdateProfile(ctx context.Context, id primitive.ObjectID, expectedVersion int64, update models.ProfileUpdate) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateProfile")
+	defer span.End()
 
-	cursor, err := collection.Find(ctx, bson.M{})
+	set := bson.M{}
+	unset := bson.M{}
+	var details []string
+
+	// The cached copy may lag behind, which would fail a correct If-Match
+	user, err := s.loadUser(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, apperrors.ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
+	if expectedVersion >= 0 && user.Version != expectedVersion {
+		return nil, ErrVersionConflict
+	}
 
-	var users []*models.User
-	if err := cursor.All(ctx, &users); err != nil {
+	for field, value := range update {
+		rule, ok := models.ProfileFieldRules[field]
+		if !ok {
+			details = append(details, fmt.Sprintf("%s: field cannot be updated", field))
+			continue
+		}
+
+		current := profileField(user, field)
+		if value == nil {
+			if current != "" {
+				unset[field] = ""
+				setProfileField(user, field, "")
+			}
+			continue
+		}
+
+		next := strings.TrimSpace(*value)
+		if next == "" {
+			details = append(details, fmt.Sprintf("%s: must not be empty, use null to clear it", field))
+			continue
+		}
+		if err := utils.ValidateVar(next, rule); err != nil {
+			details = append(details, fmt.Sprintf("%s: failed %s validation", field, rule))
+			continue
+		}
+		if next != current {
+			set[field] = next
+			setProfileField(user, field, next)
+		}
+	}
+
+	if len(details) > 0 {
+		return nil, apperrors.New(http.StatusBadRequest, "Validation error", details...)
+	}
+	if len(set) == 0 && len(unset) == 0 {
+		return user, nil
+	}
+
+	user.UpdatedAt = time.Now()
+	set["updated_at"] = user.UpdatedAt
+	change := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+	if len(unset) > 0 {
+		change["$unset"] = unset
+	}
+
+	result, err := s.collection.UpdateOne(ctx, versionFilter(id, user.Version), change)
+	if err != nil {
 		return nil, err
 	}
+	if result.MatchedCount == 0 {
+		return nil, ErrVersionConflict
+	}
 
-	return users, nil
+	user.Version++
+	s.invalidate(ctx, id)
+	return user, nil
+}
+
+func profileField(user *models.User, field string) string {
+	switch field {
+	case "display_name":
+		return user.DisplayName
+	case "locale":
+		return user.Locale
+	case "timezone":
+		return user.Timezone
+	case "avatar_url":
+		return user.AvatarURL
+	case "bio":
+		return user.Bio
+	}
+	return ""
+}
+
+func setProfileField(user *models.User, field, value string) {
+	switch field {
+	case "display_name":
+		user.DisplayName = value
+	case "locale":
+		user.Locale = value
+	case "timezone":
+		user.Timezone = value
+	case "avatar_url":
+		user.AvatarURL = value
+	case "bio":
+		user.Bio = value
+	}
+}
+
+// SetStatus moves the user to a new lifecycle status if it has not changed
+// since it was read, and bumps its version on success
+func (s *UserService) SetStatus(ctx context.Context, user *models.User, status, reason, actorID string) error {
+	ctx, span := tracing.Start(ctx, "UserService.SetStatus")
+	defer span.End()
+
+	now := time.Now()
+	result, err := s.collection.UpdateOne(
+		ctx,
+		versionFilter(user.ID, user.Version),
+		bson.M{
+			"$set": bson.M{
+				"status":            status,
+				"status_reason":     reason,
+				"status_changed_at": now,
+				"status_changed_by": actorID,
+				"updated_at":        now,
+			},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+
+	user.Status = status
+	user.StatusReason = reason
+	user.StatusChangedAt = &now
+	user.StatusChangedBy = actorID
+	user.UpdatedAt = now
+	user.Version++
+	s.invalidate(ctx, user.ID)
+	return nil
+}
+
+// MarkPendingDeletion schedules the user for purge and moves it to the
+// deleted status. It fails with ErrVersionConflict if the user already has a
+// deletion pending.
+func (s *UserService) MarkPendingDeletion(ctx context.Context, id primitive.ObjectID, requestedAt, purgeAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "UserService.MarkPendingDeletion")
+	defer span.End()
+
+	result, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "purge_at": bson.M{"$exists": false}},
+		bson.M{
+			"$set": bson.M{
+				"deletion_requested_at": requestedAt,
+				"purge_at":              purgeAt,
+				"status":                models.StatusDeleted,
+				"status_reason":         "Deletion requested by user",
+				"status_changed_at":     requestedAt,
+				"status_changed_by":     id.Hex(),
+				"updated_at":            requestedAt,
+			},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+
+	s.invalidate(ctx, id)
+	return nil
+}
+
+// ClearPendingDeletion cancels a scheduled purge and returns the user to the
+// given status. It returns ErrNoPendingDeletion once the grace period is over.
+func (s *UserService) ClearPendingDeletion(ctx context.Context, id primitive.ObjectID, status string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ClearPendingDeletion")
+	defer span.End()
+
+	now := time.Now()
+	result, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "purge_at": bson.M{"$gt": now}},
+		bson.M{
+			"$unset": bson.M{"deletion_requested_at": "", "purge_at": ""},
+			"$set": bson.M{
+				"status":            status,
+				"status_reason":     "Account restored by user",
+				"status_changed_at": now,
+				"status_changed_by": id.Hex(),
+				"updated_at":        now,
+			},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNoPendingDeletion
+	}
+
+	s.invalidate(ctx, id)
+	return nil
+}
+
+// ClaimForPurge marks a user whose grace period has ended as being purged,
+// so that it can no longer be restored and other instances leave it alone.
+// A claim older than staleAfter is taken over, as its purge did not finish.
+// It reports false if the user is not due or is claimed by someone else.
+func (s *UserService) ClaimForPurge(ctx context.Context, id primitive.ObjectID, now time.Time, staleAfter time.Duration) (bool, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ClaimForPurge")
+	defer span.End()
+
+	result, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":      id,
+			"purge_at": bson.M{"$lte": now},
+			"$or": bson.A{
+				bson.M{"purge_claimed_at": bson.M{"$exists": false}},
+				bson.M{"purge_claimed_at": bson.M{"$lte": now.Add(-staleAfter)}},
+			},
+		},
+		bson.M{"$set": bson.M{"purge_claimed_at": now}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// GetUsersDueForPurge returns users whose deletion grace period has ended
+func (s *UserService) GetUsersDueForPurge(ctx context.Context, now time.Time) ([]*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUsersDueForPurge")
+	defer span.End()
+
+	return s.findMany(ctx, bson.M{"purge_at": bson.M{"$lte": now}})
+}
+
+// PurgeUser irreversibly removes a user whose grace period has ended. It
+// reports false if the user was restored or already purged in the meantime.
+func (s *UserService) PurgeUser(ctx context.Context, id primitive.ObjectID, now time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "UserService.PurgeUser")
+	defer span.End()
+
+	var doc models.User
+	err := s.collection.FindOneAndDelete(ctx, bson.M{"_id": id, "purge_at": bson.M{"$lte": now}}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Lookups by mobile number would otherwise still find the user
+	s.users.forget(ctx, fmt.Sprintf(userMobileCacheKeyFormat, doc.MobileNumberIndex))
+	s.invalidate(ctx, id)
+	return true, nil
+}
+
+// CountUsers returns the number of user documents
+func (s *UserService) CountUsers(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CountUsers")
+	defer span.End()
+
+	return s.collection.CountDocuments(ctx, bson.M{})
+}
+
+func (s *UserService) GetUsers(ctx context.Context) ([]*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUsers")
+	defer span.End()
+
+	return s.findMany(ctx, bson.M{})
 }